@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+	"github.com/joy-currency-conversion-GCP/shared/favorites"
+)
+
+var (
+	ErrFavoriteNotFound = favorites.ErrNotFound
+	ErrInvalidFavorite  = errors.New("invalid favorite")
+)
+
+// FavoriteUpdate holds the fields a PATCH may change; nil means unchanged
+type FavoriteUpdate struct {
+	Threshold           *float64
+	CurrencyDestination *string
+	Channel             *string
+	Target              *string
+}
+
+// UpdateFavorite applies update to the favorite and returns the stored
+// result. Changing a favorite re-arms its alert.
+func (s *Server) UpdateFavorite(ctx context.Context, id int64, update FavoriteUpdate) (*domain.FavoriteConversion, error) {
+	return s.favorites.Update(ctx, id, func(fav *domain.FavoriteConversion) error {
+		if update.Threshold != nil {
+			fav.Threshold = *update.Threshold
+		}
+		if update.CurrencyDestination != nil {
+			fav.CurrencyDestination = *update.CurrencyDestination
+		}
+		if update.Channel != nil && *update.Channel != fav.Channel {
+			// The old target belongs to the old channel
+			fav.Channel, fav.Target = *update.Channel, ""
+		}
+		if update.Target != nil {
+			fav.Target = *update.Target
+		}
+		if err := domain.ValidateChannel(fav.Channel, fav.Target); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidFavorite, err)
+		}
+		if fav.CurrencyDestination == fav.CurrencyOrigin {
+			return fmt.Errorf("%w: currency_origin and currency_destination must differ", ErrInvalidFavorite)
+		}
+		if _, err := domain.ValidateRule(fav.RuleType, fav.Direction, domain.FormatWindow(fav.Window), fav.Threshold); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidFavorite, err)
+		}
+
+		fav.Armed = true
+		return nil
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// historyIntervals are the bucket sizes accepted by /rates/history
+var historyIntervals = map[string]time.Duration{
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+// maxHistoryBuckets bounds the size of a single history response
+const maxHistoryBuckets = 5000
+
+// OHLCPoint summarizes the rates observed within one time bucket
+type OHLCPoint struct {
+	Time    time.Time `json:"time"`
+	Open    float64   `json:"open"`
+	High    float64   `json:"high"`
+	Low     float64   `json:"low"`
+	Close   float64   `json:"close"`
+	Samples int       `json:"samples"`
+}
+
+// HistoryResponse represents the response of /rates/history
+type HistoryResponse struct {
+	From     string      `json:"from"`
+	To       string      `json:"to"`
+	Interval string      `json:"interval"`
+	Start    time.Time   `json:"start"`
+	End      time.Time   `json:"end"`
+	Points   []OHLCPoint `json:"points"`
+}
+
+// GetRateHistory returns the from->to rates observed in [start, end) grouped
+// into OHLC buckets. Any pair is crossed from the snapshots the worker
+// stores, where every quote of one provider fetch shares base and time.
+func (s *Server) GetRateHistory(ctx context.Context, from, to string, start, end time.Time, interval time.Duration) ([]OHLCPoint, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(`
+		SELECT a.observed_at, b.rate / a.rate
+		FROM exchange_rates a
+		JOIN exchange_rates b
+		  ON b.base = a.base AND b.provider = a.provider AND b.observed_at = a.observed_at
+		WHERE a.quote = ? AND b.quote = ? AND a.observed_at >= ? AND a.observed_at < ?
+		ORDER BY a.observed_at
+	`), from, to, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rate history: %w", err)
+	}
+	defer rows.Close()
+
+	points := []OHLCPoint{}
+	for rows.Next() {
+		var observedAt time.Time
+		var rate float64
+		if err := rows.Scan(&observedAt, &rate); err != nil {
+			return nil, fmt.Errorf("failed to scan rate history: %w", err)
+		}
+
+		bucket := observedAt.UTC().Truncate(interval)
+		if n := len(points); n > 0 && points[n-1].Time.Equal(bucket) {
+			p := &points[n-1]
+			p.High = max(p.High, rate)
+			p.Low = min(p.Low, rate)
+			p.Close = rate
+			p.Samples++
+			continue
+		}
+
+		points = append(points, OHLCPoint{
+			Time:    bucket,
+			Open:    rate,
+			High:    rate,
+			Low:     rate,
+			Close:   rate,
+			Samples: 1,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rate history: %w", err)
+	}
+
+	return points, nil
+}
+
+// parseHistoryTime accepts RFC 3339 timestamps or plain YYYY-MM-DD dates
+// (UTC). A date given as the end of the range includes that whole day.
+func parseHistoryTime(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, use RFC 3339 or YYYY-MM-DD", value)
+}
@@ -1,126 +1,141 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"log"
+	"regexp"
+	"time"
 
-	mysql "github.com/go-sql-driver/mysql"
 	"github.com/joy-currency-conversion-GCP/config"
+	"github.com/joy-currency-conversion-GCP/shared/dialect"
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+	"github.com/joy-currency-conversion-GCP/shared/favorites"
+	"github.com/joy-currency-conversion-GCP/shared/rates"
+	"github.com/joy-currency-conversion-GCP/shared/schema"
 )
 
-var ErrEmailAlreadyExists = errors.New("favorite with this email already exists")
-
-// ExchangeRatesResponse represents the response from exchangeratesapi.io
-type ExchangeRatesResponse struct {
-	Success   bool              `json:"success"`
-	Timestamp int64             `json:"timestamp"`
-	Base      string            `json:"base"`
-	Date      string            `json:"date"`
-	Rates     map[string]float64 `json:"rates"`
-}
+var (
+	ErrFavoriteAlreadyExists = favorites.ErrAlreadyExists
+	ErrInvalidCurrency       = errors.New("invalid currency code")
+)
 
 // ConversionResponse represents the response to send to the user
 type ConversionResponse struct {
-	From      string  `json:"from"`
-	To        string  `json:"to"`
-	Rate      float64 `json:"rate"`
-	Date      string  `json:"date"`
-	Timestamp int64   `json:"timestamp"`
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Amount    float64  `json:"amount"`
+	Result    float64  `json:"result"`
+	Rate      float64  `json:"rate"`
+	Date      string   `json:"date"`
+	Timestamp int64    `json:"timestamp"`
+	Sources   []string `json:"sources"`
+
+	// Freshness of the underlying rate table, reported through headers
+	FetchedAt time.Time `json:"-"`
+	Cached    bool      `json:"-"`
 }
 
-// ConvertEURToCOP fetches exchange rates and extracts COP rate
-func ConvertEURToCOP(apiKey string) (*ConversionResponse, error) {
-	// Build the API URL
-	url := fmt.Sprintf("https://api.exchangeratesapi.io/v1/latest?access_key=%s&base=EUR", apiKey)
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
 
-	// Make HTTP request
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to call exchange rates API: %w", err)
+// ValidateCurrencyCode checks that code looks like an ISO 4217 alphabetic code
+func ValidateCurrencyCode(code string) error {
+	if !currencyCodePattern.MatchString(code) {
+		return fmt.Errorf("%w: %q", ErrInvalidCurrency, code)
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("exchange rates API returned status %d: %s", resp.StatusCode, string(body))
+// lookupRates serves base from the cache when it is enabled
+func (s *Server) lookupRates(ctx context.Context, base string) (*rates.Table, rates.CacheStatus, error) {
+	if s.rateCache != nil {
+		return s.rateCache.Lookup(ctx, base)
 	}
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
+	table, err := s.rates.Latest(ctx, base)
+	return table, rates.CacheStatus{FetchedAt: time.Now()}, err
+}
 
-	// Parse JSON response
-	var exchangeResp ExchangeRatesResponse
-	if err := json.Unmarshal(body, &exchangeResp); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+// ValidateSupportedCurrencies checks the format of every code and that the
+// rates provider quotes it
+func (s *Server) ValidateSupportedCurrencies(ctx context.Context, codes ...string) error {
+	for _, code := range codes {
+		if err := ValidateCurrencyCode(code); err != nil {
+			return err
+		}
 	}
 
-	// Check if API call was successful
-	if !exchangeResp.Success {
-		return nil, fmt.Errorf("exchange rates API returned success=false")
+	table, _, err := s.lookupRates(ctx, domain.RatesBase)
+	if err != nil {
+		return fmt.Errorf("failed to load supported currencies: %w", err)
 	}
 
-	// Extract COP rate
-	copRate, exists := exchangeResp.Rates["COP"]
-	if !exists {
-		return nil, fmt.Errorf("COP rate not found in API response")
+	for _, code := range codes {
+		if !table.Supports(code) {
+			return fmt.Errorf("%w: %s", rates.ErrUnsupportedCurrency, code)
+		}
 	}
-
-	// Build response
-	result := &ConversionResponse{
-		From:      "EUR",
-		To:        "COP",
-		Rate:      copRate,
-		Date:      exchangeResp.Date,
-		Timestamp: exchangeResp.Timestamp,
-	}
-
-	return result, nil
+	return nil
 }
 
-var mysqlDB *sql.DB
+// Convert converts amount from one currency to another. Rates are always
+// fetched against the EUR base and crossed, so any pair listed by the
+// provider can be converted.
+func (s *Server) Convert(ctx context.Context, from, to string, amount float64) (*ConversionResponse, error) {
+	if err := ValidateCurrencyCode(from); err != nil {
+		return nil, err
+	}
+	if err := ValidateCurrencyCode(to); err != nil {
+		return nil, err
+	}
 
-func InitMySQLFromEnv(cfg *config.Config) error {
-	db, err := cfg.DBConfig.Connect()
+	table, status, err := s.lookupRates(ctx, domain.RatesBase)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	mysqlDB = db
+	rate, err := table.Rate(from, to)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := config.InitSchema(mysqlDB); err != nil {
-		return err
+	result := &ConversionResponse{
+		From:      from,
+		To:        to,
+		Amount:    amount,
+		Result:    amount * rate,
+		Rate:      rate,
+		Date:      table.Date,
+		Timestamp: table.Timestamp,
+		Sources:   table.SourcesFor(from, to),
+		FetchedAt: status.FetchedAt,
+		Cached:    status.Hit,
 	}
 
-	return nil
+	return result, nil
 }
 
-func SaveFavoriteConversion(email, currencyOrigin, currencyDestination string, threshold float64) (int64, error) {
-	if mysqlDB == nil {
-		return 0, fmt.Errorf("database is not initialized")
+// OpenDB connects to the configured database and applies pending migrations
+// unless MIGRATE_ON_START is false
+func OpenDB(cfg *config.Config) (*sql.DB, dialect.Dialect, error) {
+	d, err := dialect.For(cfg.DBConfig.Driver)
+	if err != nil {
+		return nil, d, err
 	}
 
-	res, err := mysqlDB.Exec(
-		`INSERT INTO favorite_conversions (email, currency_origin, currency_destination, threshold) VALUES (?, ?, ?, ?)`,
-		email, currencyOrigin, currencyDestination, threshold,
-	)
+	db, err := cfg.DBConfig.Connect()
 	if err != nil {
-		if me, ok := err.(*mysql.MySQLError); ok && me.Number == 1062 {
-            return 0, ErrEmailAlreadyExists
-        }
-		return 0, fmt.Errorf("failed to insert favorite conversion: %w", err)
+		return nil, d, err
 	}
 
-	id, err := res.LastInsertId()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get inserted id: %w", err)
+	if !cfg.MigrateOnStart {
+		log.Println("⏭️ MIGRATE_ON_START=false, skipping schema migrations")
+		return db, d, nil
+	}
+	if err := schema.Migrate(context.Background(), db, d.Driver, cfg.MigrateLockTimeout); err != nil {
+		return nil, d, err
 	}
-	return id, nil
-}
\ No newline at end of file
+	return db, d, nil
+}
@@ -3,10 +3,20 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
-	
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/joy-currency-conversion-GCP/config"
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+	"github.com/joy-currency-conversion-GCP/shared/favorites"
+	"github.com/joy-currency-conversion-GCP/shared/rates"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
@@ -32,63 +42,372 @@ func getSecret(secretName string) string {
 	return string(result.Payload.Data)
 }
 
-var appConfig *config.Config
-
 func main() {
-	appConfig = config.Load()
-	if err := InitMySQLFromEnv(appConfig); err != nil {
-		log.Fatalf("failed to initialize MySQL: %v", err)
+	cfg := config.Load()
+
+	db, d, err := OpenDB(cfg)
+	if err != nil {
+		log.Fatalf("failed to initialize database: %v", err)
+	}
+	provider, err := rates.New(cfg.Rates)
+	if err != nil {
+		log.Fatalf("failed to initialize rates provider: %v", err)
 	}
 
-	http.HandleFunc("/convert", convertHandler)
-	http.HandleFunc("/favorites", favoritesHandler)
+	srv := NewServer(cfg, favorites.NewSQL(db, d), provider, db, d)
 
-	addr := ":" + appConfig.Port
-	log.Printf("🚀 Servidor escuchando en %s (ambiente: %s)", addr, appConfig.Environment)
+	addr := ":" + cfg.Port
+	log.Printf("🚀 Servidor escuchando en %s (ambiente: %s)", addr, cfg.Environment)
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	if err := http.ListenAndServe(addr, srv); err != nil {
 		log.Fatalf("❌ Error iniciando servidor: %v", err)
 	}
 }
 
-func convertHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) convertHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	result, err := ConvertEURToCOP(appConfig.APIKey)
+	query := r.URL.Query()
+	from := strings.ToUpper(strings.TrimSpace(query.Get("from")))
+	to := strings.ToUpper(strings.TrimSpace(query.Get("to")))
+	if from == "" {
+		from = "EUR"
+	}
+	if to == "" {
+		to = "COP"
+	}
+
+	amount := 1.0
+	if raw := query.Get("amount"); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil || parsed < 0 || math.IsInf(parsed, 0) || math.IsNaN(parsed) {
+			http.Error(w, "amount must be a non-negative number", http.StatusBadRequest)
+			return
+		}
+		amount = parsed
+	}
+
+	result, err := s.Convert(r.Context(), from, to, amount)
 	if err != nil {
+		if errors.Is(err, ErrInvalidCurrency) || errors.Is(err, rates.ErrUnsupportedCurrency) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	source := "live"
+	if result.Cached {
+		source = "cache"
+	}
+	age := int64(time.Since(result.FetchedAt).Seconds())
+	if age < 0 {
+		age = 0
+	}
+
 	// Set content type and return JSON response
+	w.Header().Set("Age", strconv.FormatInt(age, 10))
+	w.Header().Set("X-Rate-Source", source)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
 
+func (s *Server) ratesHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	from := strings.ToUpper(strings.TrimSpace(query.Get("from")))
+	to := strings.ToUpper(strings.TrimSpace(query.Get("to")))
+	for _, code := range []string{from, to} {
+		if err := ValidateCurrencyCode(code); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	intervalName := query.Get("interval")
+	if intervalName == "" {
+		intervalName = "1d"
+	}
+	interval, ok := historyIntervals[intervalName]
+	if !ok {
+		http.Error(w, "interval must be 1h or 1d", http.StatusBadRequest)
+		return
+	}
+
+	end := time.Now().UTC()
+	if raw := query.Get("end"); raw != "" {
+		parsed, err := parseHistoryTime(raw, true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		end = parsed
+	}
+	start := end.Add(-7 * 24 * time.Hour)
+	if raw := query.Get("start"); raw != "" {
+		parsed, err := parseHistoryTime(raw, false)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		start = parsed
+	}
+
+	if !start.Before(end) {
+		http.Error(w, "start must be before end", http.StatusBadRequest)
+		return
+	}
+	if end.Sub(start)/interval > maxHistoryBuckets {
+		http.Error(w, fmt.Sprintf("range too large for interval %s (max %d buckets)", intervalName, maxHistoryBuckets), http.StatusBadRequest)
+		return
+	}
+
+	points, err := s.GetRateHistory(r.Context(), from, to, start, end, interval)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HistoryResponse{
+		From:     from,
+		To:       to,
+		Interval: intervalName,
+		Start:    start.UTC(),
+		End:      end.UTC(),
+		Points:   points,
+	})
+}
+
 type FavoriteRequest struct {
 	Email               string  `json:"email"`
 	CurrencyOrigin      string  `json:"currency_origin"`
 	CurrencyDestination string  `json:"currency_destination"`
 	Threshold           float64 `json:"threshold"`
+	Direction           string  `json:"direction"`
+	Rule                string  `json:"rule"`
+	Window              string  `json:"window,omitempty"`
+	Channel             string  `json:"channel"`
+	Target              string  `json:"target,omitempty"`
 }
 
 type FavoriteResponse struct {
-	ID                  int64   `json:"id"`
-	Email               string  `json:"email"`
-	CurrencyOrigin      string  `json:"currency_origin"`
-	CurrencyDestination string  `json:"currency_destination"`
-	Threshold           float64 `json:"threshold"`
+	ID                  int64      `json:"id"`
+	Email               string     `json:"email"`
+	CurrencyOrigin      string     `json:"currency_origin"`
+	CurrencyDestination string     `json:"currency_destination"`
+	Threshold           float64    `json:"threshold"`
+	Direction           string     `json:"direction"`
+	Rule                string     `json:"rule"`
+	Window              string     `json:"window,omitempty"`
+	Armed               bool       `json:"armed"`
+	LastNotifiedAt      *time.Time `json:"last_notified_at,omitempty"`
+	LastNotifiedRate    *float64   `json:"last_notified_rate,omitempty"`
+	Channel             string     `json:"channel"`
+	Target              string     `json:"target,omitempty"`
+}
+
+type FavoritePatchRequest struct {
+	Threshold           *float64 `json:"threshold"`
+	CurrencyDestination *string  `json:"currency_destination"`
+	Channel             *string  `json:"channel"`
+	Target              *string  `json:"target"`
 }
 
-func favoritesHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+// maskTarget keeps only the scheme and host of a target URL. Webhook URLs
+// often carry their credentials in the path or query and favorites are
+// readable by anyone who knows the email or id.
+func maskTarget(target string) string {
+	if target == "" {
+		return ""
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + "/..."
+}
+
+func newFavoriteResponse(fav *domain.FavoriteConversion) FavoriteResponse {
+	resp := FavoriteResponse{
+		ID:                  fav.ID,
+		Email:               fav.Email,
+		CurrencyOrigin:      fav.CurrencyOrigin,
+		CurrencyDestination: fav.CurrencyDestination,
+		Threshold:           fav.Threshold,
+		Direction:           fav.Direction,
+		Rule:                fav.RuleType,
+		Window:              domain.FormatWindow(fav.Window),
+		Armed:               fav.Armed,
+		Channel:             fav.Channel,
+		Target:              maskTarget(fav.Target),
+	}
+	if fav.LastNotifiedAt.Valid {
+		resp.LastNotifiedAt = &fav.LastNotifiedAt.Time
+	}
+	if fav.LastNotifiedRate.Valid {
+		resp.LastNotifiedRate = &fav.LastNotifiedRate.Float64
+	}
+	return resp
+}
+
+func (s *Server) favoritesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		s.createFavorite(w, r)
+	case http.MethodGet:
+		s.listFavorites(w, r)
+	default:
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// favoriteHandler serves /favorites/{id}
+func (s *Server) favoriteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "invalid favorite id", http.StatusBadRequest)
 		return
 	}
 
+	switch r.Method {
+	case http.MethodGet:
+		s.getFavorite(w, r, id)
+	case http.MethodPatch:
+		s.patchFavorite(w, r, id)
+	case http.MethodDelete:
+		s.deleteFavorite(w, r, id)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) listFavorites(w http.ResponseWriter, r *http.Request) {
+	email := strings.TrimSpace(r.URL.Query().Get("email"))
+	if email == "" {
+		http.Error(w, "email query parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	favorites, err := s.favorites.ListByEmail(r.Context(), email)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]FavoriteResponse, 0, len(favorites))
+	for i := range favorites {
+		resp = append(resp, newFavoriteResponse(&favorites[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) getFavorite(w http.ResponseWriter, r *http.Request, id int64) {
+	fav, err := s.favorites.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrFavoriteNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newFavoriteResponse(fav))
+}
+
+func (s *Server) patchFavorite(w http.ResponseWriter, r *http.Request, id int64) {
+	var req FavoritePatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Threshold == nil && req.CurrencyDestination == nil && req.Channel == nil && req.Target == nil {
+		http.Error(w, "threshold, currency_destination, channel or target is required", http.StatusBadRequest)
+		return
+	}
+	if req.Channel != nil {
+		channel := strings.ToLower(strings.TrimSpace(*req.Channel))
+		req.Channel = &channel
+	}
+	if req.Target != nil {
+		target := strings.TrimSpace(*req.Target)
+		req.Target = &target
+	}
+	if req.CurrencyDestination != nil {
+		destination := strings.ToUpper(strings.TrimSpace(*req.CurrencyDestination))
+		if !s.validateCurrencies(w, r, destination) {
+			return
+		}
+		req.CurrencyDestination = &destination
+	}
+
+	fav, err := s.UpdateFavorite(r.Context(), id, FavoriteUpdate{
+		Threshold:           req.Threshold,
+		CurrencyDestination: req.CurrencyDestination,
+		Channel:             req.Channel,
+		Target:              req.Target,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrFavoriteNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrInvalidFavorite):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrFavoriteAlreadyExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newFavoriteResponse(fav))
+}
+
+func (s *Server) deleteFavorite(w http.ResponseWriter, r *http.Request, id int64) {
+	if err := s.favorites.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, ErrFavoriteNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateCurrencies writes the error response and returns false when a
+// code is malformed or not supported by the rates provider
+func (s *Server) validateCurrencies(w http.ResponseWriter, r *http.Request, codes ...string) bool {
+	err := s.ValidateSupportedCurrencies(r.Context(), codes...)
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, ErrInvalidCurrency) || errors.Is(err, rates.ErrUnsupportedCurrency) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+	return false
+}
+
+func (s *Server) createFavorite(w http.ResponseWriter, r *http.Request) {
 	var req FavoriteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid JSON body", http.StatusBadRequest)
@@ -100,18 +419,62 @@ func favoritesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req.CurrencyOrigin = strings.ToUpper(strings.TrimSpace(req.CurrencyOrigin))
+	req.CurrencyDestination = strings.ToUpper(strings.TrimSpace(req.CurrencyDestination))
 	if req.CurrencyOrigin == "" {
 		req.CurrencyOrigin = "EUR"
 	}
-	if req.CurrencyOrigin != "EUR" {
-		http.Error(w, "currency_origin must be EUR", http.StatusBadRequest)
+	req.Direction = strings.ToLower(strings.TrimSpace(req.Direction))
+	if req.Direction == "" {
+		req.Direction = domain.DirectionAbove
+	}
+	if !domain.ValidDirection(req.Direction) {
+		http.Error(w, "direction must be above, below, crosses_up or crosses_down", http.StatusBadRequest)
+		return
+	}
+	req.Rule = strings.ToLower(strings.TrimSpace(req.Rule))
+	if req.Rule == "" {
+		req.Rule = domain.RuleThreshold
+	}
+	window, err := domain.ValidateRule(req.Rule, req.Direction, strings.TrimSpace(req.Window), req.Threshold)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.CurrencyOrigin == req.CurrencyDestination {
+		http.Error(w, "currency_origin and currency_destination must differ", http.StatusBadRequest)
+		return
+	}
+	req.Channel = strings.ToLower(strings.TrimSpace(req.Channel))
+	if req.Channel == "" {
+		req.Channel = domain.ChannelEmail
+	}
+	req.Target = strings.TrimSpace(req.Target)
+	if err := domain.ValidateChannel(req.Channel, req.Target); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.validateCurrencies(w, r, req.CurrencyOrigin, req.CurrencyDestination) {
 		return
 	}
 
-	id, err := SaveFavoriteConversion(req.Email, req.CurrencyOrigin, req.CurrencyDestination, req.Threshold)
+	fav := &domain.FavoriteConversion{
+		Email:               req.Email,
+		CurrencyOrigin:      req.CurrencyOrigin,
+		CurrencyDestination: req.CurrencyDestination,
+		Threshold:           req.Threshold,
+		Direction:           req.Direction,
+		RuleType:            req.Rule,
+		Window:              window,
+		Armed:               true,
+		Channel:             req.Channel,
+		Target:              req.Target,
+	}
+
+	fav.ID, err = s.favorites.Create(r.Context(), fav)
 	if err != nil {
-		if err == ErrEmailAlreadyExists {
-			http.Error(w, "favorite with this email already exists", http.StatusConflict)
+		if errors.Is(err, ErrFavoriteAlreadyExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
 			return
 		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -119,11 +482,5 @@ func favoritesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(FavoriteResponse{
-		ID:                  id,
-		Email:               req.Email,
-		CurrencyOrigin:      req.CurrencyOrigin,
-		CurrencyDestination: req.CurrencyDestination,
-		Threshold:           req.Threshold,
-	})
+	json.NewEncoder(w).Encode(newFavoriteResponse(fav))
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/joy-currency-conversion-GCP/config"
+	"github.com/joy-currency-conversion-GCP/shared/dialect"
+	"github.com/joy-currency-conversion-GCP/shared/favorites"
+	"github.com/joy-currency-conversion-GCP/shared/rates"
+)
+
+// Server is the api with everything its handlers depend on. Each one has its
+// own routes, so several can run side by side.
+type Server struct {
+	config    *config.Config
+	favorites favorites.Repository
+	rates     rates.RateProvider
+	rateCache *rates.Cache // nil when RATES_CACHE_TTL is 0
+
+	// db holds the rates the worker records, for /rates/history. It may be
+	// nil, which only fails that endpoint.
+	db      *sql.DB
+	dialect dialect.Dialect
+
+	mux *http.ServeMux
+}
+
+// NewServer builds a Server and registers its routes
+func NewServer(cfg *config.Config, repo favorites.Repository, provider rates.RateProvider, db *sql.DB, d dialect.Dialect) *Server {
+	s := &Server{
+		config:    cfg,
+		favorites: repo,
+		rates:     provider,
+		db:        db,
+		dialect:   d,
+		mux:       http.NewServeMux(),
+	}
+	if cfg.RatesCacheTTL > 0 {
+		s.rateCache = rates.NewCache(provider, cfg.RatesCacheTTL)
+	}
+
+	s.mux.HandleFunc("/convert", s.convertHandler)
+	s.mux.HandleFunc("/favorites", s.favoritesHandler)
+	s.mux.HandleFunc("/favorites/{id}", s.favoriteHandler)
+	s.mux.HandleFunc("/rates/history", s.ratesHistoryHandler)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/config"
+	"github.com/joy-currency-conversion-GCP/shared/dialect"
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+	"github.com/joy-currency-conversion-GCP/shared/favorites"
+	"github.com/joy-currency-conversion-GCP/shared/rates"
+)
+
+// newTestServer runs a Server on an in-memory repository and a static
+// provider quoting usd US dollars per euro
+func newTestServer(t *testing.T, usd float64) *httptest.Server {
+	t.Helper()
+	provider := rates.NewStaticProvider(&rates.Table{
+		Base:      "EUR",
+		Timestamp: time.Now().Unix(),
+		Rates:     map[string]float64{"EUR": 1, "USD": usd, "GBP": 0.85},
+		Source:    "static",
+	})
+	srv := httptest.NewServer(NewServer(&config.Config{RatesCacheTTL: time.Minute}, favorites.NewMemory(), provider, nil, dialect.Dialect{}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestServersDoNotShareFavorites(t *testing.T) {
+	a, b := newTestServer(t, 1.1), newTestServer(t, 1.1)
+
+	createFavorite := func(srv *httptest.Server) int {
+		body := `{"email":"ana@example.com","currency_destination":"USD","threshold":1.2}`
+		resp, err := http.Post(srv.URL+"/favorites", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := createFavorite(a); status != http.StatusOK {
+		t.Fatalf("create on a: status %d", status)
+	}
+	// The same favorite would be a duplicate if both servers shared a repository
+	if status := createFavorite(b); status != http.StatusOK {
+		t.Fatalf("create on b: status %d, want %d", status, http.StatusOK)
+	}
+	if status := createFavorite(a); status != http.StatusConflict {
+		t.Fatalf("duplicate create on a: status %d, want %d", status, http.StatusConflict)
+	}
+
+	resp, err := http.Get(b.URL + "/favorites?email=ana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var listed []FavoriteResponse
+	if err := json.NewDecoder(resp.Body).Decode(&listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 {
+		t.Errorf("b lists %d favorites, want 1", len(listed))
+	}
+}
+
+func TestServersDoNotShareRates(t *testing.T) {
+	servers := map[float64]*httptest.Server{1.1: newTestServer(t, 1.1), 1.3: newTestServer(t, 1.3)}
+
+	for want, srv := range servers {
+		resp, err := http.Get(srv.URL + "/convert?from=EUR&to=USD&amount=10")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var result ConversionResponse
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Rate != want {
+			t.Errorf("rate %v, want %v from the server's own provider and cache", result.Rate, want)
+		}
+	}
+}
+
+// wrappingRepository adds context to the errors Create returns, as a
+// repository layered over another one would
+type wrappingRepository struct {
+	favorites.Repository
+}
+
+func (r wrappingRepository) Create(ctx context.Context, fav *domain.FavoriteConversion) (int64, error) {
+	id, err := r.Repository.Create(ctx, fav)
+	if err != nil {
+		return 0, fmt.Errorf("creating favorite: %w", err)
+	}
+	return id, nil
+}
+
+func TestCreateFavoriteWrappedDuplicate(t *testing.T) {
+	provider := rates.NewStaticProvider(&rates.Table{Base: "EUR", Timestamp: time.Now().Unix(), Rates: map[string]float64{"EUR": 1, "USD": 1.1}, Source: "static"})
+	srv := httptest.NewServer(NewServer(&config.Config{RatesCacheTTL: time.Minute}, wrappingRepository{favorites.NewMemory()}, provider, nil, dialect.Dialect{}))
+	defer srv.Close()
+
+	var statuses []int
+	for range 2 {
+		resp, err := http.Post(srv.URL+"/favorites", "application/json",
+			strings.NewReader(`{"email":"ana@example.com","currency_destination":"USD","threshold":1.2}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		statuses = append(statuses, resp.StatusCode)
+	}
+	if statuses[0] != http.StatusOK || statuses[1] != http.StatusConflict {
+		t.Errorf("statuses %v, want [200 409]", statuses)
+	}
+}
@@ -34,10 +34,28 @@ def send_email_notification(request):
     currency_destination = data.get('currency_destination')
     current_rate = data.get('current_rate')
     threshold = data.get('threshold')
+    direction = data.get('direction', 'above')
+    rule = data.get('rule', 'threshold')
+    window = data.get('window', '')
+    observed_value = data.get('observed_value')
     
     if not all([email, currency_origin, currency_destination, current_rate, threshold]):
         return jsonify({"error": "missing required fields"}), 400
     
+    position = {
+        'above': 'above',
+        'below': 'below',
+        'crosses_up': 'above (it just crossed upwards)',
+        'crosses_down': 'below (it just crossed downwards)',
+    }.get(direction, 'above')
+
+    if rule == 'pct_change':
+        summary = f"The rate moved {observed_value:+.2f}% within {window} (your threshold: {threshold}%)."
+    elif rule == 'volatility':
+        summary = f"The {window} standard deviation is {observed_value:.4f}, now {position} your threshold."
+    else:
+        summary = f"The current rate is now {position} your configured threshold."
+
     subject = f"Currency Alert: {currency_origin} to {currency_destination}"
     body = f"""
     Hello,
@@ -50,7 +68,7 @@ def send_email_notification(request):
     - Current Rate: {current_rate}
     - Your Threshold: {threshold}
     
-    The current rate is now above your configured threshold.
+    {summary}
     
     Best regards,
     Currency Conversion Service
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/rates"
+)
+
+// Common is the part of the configuration every service shares. Service
+// configs embed it.
+type Common struct {
+	Port               string
+	Environment        Environment
+	Rates              rates.Options
+	MigrateOnStart     bool          // Aplica las migraciones pendientes al arrancar
+	MigrateLockTimeout time.Duration // Espera máxima por el lock de migraciones
+	DBConfig           DatabaseConfig
+}
+
+// LoadCommon carga la configuración compartida del entorno detectado
+func LoadCommon() Common {
+	common := LoadDatabaseCommon()
+	common.Port = GetEnv("PORT", "8080")
+	common.Rates = LoadRatesOptions(common.Environment)
+	common.MigrateOnStart = GetEnvBool("MIGRATE_ON_START", true)
+	return common
+}
+
+// LoadDatabaseCommon carga solo el entorno, la base de datos y la espera
+// por el lock de migraciones, para comandos que no usan nada más
+func LoadDatabaseCommon() Common {
+	env := DetectEnvironment()
+
+	return Common{
+		Environment:        env,
+		MigrateLockTimeout: GetEnvDuration("MIGRATE_LOCK_TIMEOUT", time.Minute),
+		DBConfig:           LoadDatabaseConfig(env),
+	}
+}
+
+func (c *Common) IsProduction() bool {
+	return c.Environment == EnvProduction
+}
+
+// LoadRatesOptions reads the rate provider settings, loading only the
+// secrets of the selected providers
+func LoadRatesOptions(env Environment) rates.Options {
+	opts := rates.Options{
+		Providers:            SplitList(GetEnv("RATE_PROVIDER", rates.ProviderExchangeRatesAPI)),
+		Mode:                 GetEnv("RATE_MODE", rates.ModeFallback),
+		MaxDeviation:         GetEnvFloat("RATE_MAX_DEVIATION", 0.02),
+		MinSources:           GetEnvInt("RATE_MIN_SOURCES", 2),
+		ExchangeRatesAPIURL:  os.Getenv("EXCHANGE_RATES_API_URL"),
+		OpenExchangeRatesURL: os.Getenv("OPEN_EXCHANGE_RATES_URL"),
+		FixturePath:          os.Getenv("RATES_FIXTURE_PATH"),
+	}
+
+	log.Printf("💱 Proveedores de tasas: %v (modo: %s)", opts.Providers, opts.Mode)
+
+	for _, provider := range opts.Providers {
+		switch provider {
+		case rates.ProviderExchangeRatesAPI:
+			opts.ExchangeRatesAPIKey = LoadSecret("EXCHANGE_RATES_API_KEY", "EXCHANGE_RATES_API_KEY", env)
+		case rates.ProviderOpenExchangeRates:
+			opts.OpenExchangeRatesAppID = LoadSecret("OPEN_EXCHANGE_RATES_APP_ID", "OPEN_EXCHANGE_RATES_APP_ID", env)
+		}
+	}
+
+	return opts
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/joy-currency-conversion-GCP/shared/dialect"
+	_ "modernc.org/sqlite"
+)
+
+type DatabaseConfig struct {
+	Driver         string // mysql, postgres o sqlite
+	Path           string // Archivo de la base SQLite
+	Host           string
+	Port           string
+	Name           string
+	User           string
+	Password       string
+	ConnectionName string // Para Cloud SQL (proyecto:region:instancia)
+	SSLMode        string // Solo PostgreSQL por TCP
+	MaxOpenConns   int
+}
+
+func LoadDatabaseConfig(env Environment) DatabaseConfig {
+	maxOpenConns := GetEnvInt("DB_MAX_OPEN_CONNS", 10)
+
+	driver := GetEnv("DB_DRIVER", dialect.MySQL)
+	engine, defaultHost, defaultPort := "MySQL", "mysql", "3306"
+
+	switch driver {
+	case dialect.MySQL:
+	case dialect.Postgres:
+		engine, defaultHost, defaultPort = "PostgreSQL", "postgres", "5432"
+	case dialect.SQLite:
+		log.Println("🗃️ Configurando SQLite embebido (sin servidor MySQL)")
+
+		return DatabaseConfig{
+			Driver:       driver,
+			Path:         GetEnv("SQLITE_PATH", "currency_conversion.db"),
+			MaxOpenConns: maxOpenConns,
+		}
+	default:
+		log.Fatalf("❌ DB_DRIVER desconocido: %s (usa mysql, postgres o sqlite)", driver)
+	}
+
+	if env == EnvProduction {
+		log.Printf("📦 Configurando Cloud SQL %s (producción)", engine)
+
+		return DatabaseConfig{
+			Driver:         driver,
+			ConnectionName: GetEnv("CLOUD_SQL_CONNECTION_NAME", ""),
+			Name:           GetEnv("DB_NAME", "currency_conversion"),
+			User:           LoadSecret("DB_USER", "DB_USER", env),
+			Password:       LoadSecret("DB_PASSWORD", "DB_PASSWORD", env),
+			MaxOpenConns:   maxOpenConns,
+		}
+	}
+
+	log.Printf("💻 Configurando %s local (desarrollo)", engine)
+
+	return DatabaseConfig{
+		Driver:       driver,
+		Host:         GetEnv("DB_HOST", defaultHost),
+		Port:         GetEnv("DB_PORT", defaultPort),
+		Name:         GetEnv("DB_NAME", "currency_conversion"),
+		User:         GetEnv("DB_USER", "app"),
+		Password:     GetEnv("DB_PASSWORD", "app_password"),
+		SSLMode:      GetEnv("DB_SSLMODE", "disable"),
+		MaxOpenConns: maxOpenConns,
+	}
+}
+
+func (db *DatabaseConfig) GetDSN() string {
+	if db.Driver == dialect.SQLite {
+		// Writers wait for each other instead of failing with SQLITE_BUSY, and
+		// times are stored in a format that sorts and compares as text
+		log.Printf("📡 DSN SQLite: %s", db.Path)
+		return fmt.Sprintf("file:%s?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_time_format=sqlite&_txlock=immediate", db.Path)
+	}
+
+	if db.Driver == dialect.Postgres {
+		return db.postgresDSN()
+	}
+
+	if db.ConnectionName != "" {
+		socketPath := fmt.Sprintf("/cloudsql/%s", db.ConnectionName)
+		dsn := fmt.Sprintf("%s:%s@unix(%s)/%s?parseTime=true",
+			db.User,
+			db.Password,
+			socketPath,
+			db.Name,
+		)
+		log.Printf("📡 DSN Cloud SQL: %s@unix(/cloudsql/...)/%s", db.User, db.Name)
+		return dsn
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
+		db.User,
+		db.Password,
+		db.Host,
+		db.Port,
+		db.Name,
+	)
+	log.Printf("📡 DSN Local: %s@tcp(%s:%s)/%s", db.User, db.Host, db.Port, db.Name)
+	return dsn
+}
+
+// postgresDSN builds a keyword/value DSN. On Cloud SQL the host is the
+// directory of the instance's unix socket.
+func (db *DatabaseConfig) postgresDSN() string {
+	host, params := db.Host, fmt.Sprintf("port=%s sslmode=%s", pgValue(db.Port), pgValue(db.SSLMode))
+	if db.ConnectionName != "" {
+		host, params = "/cloudsql/"+db.ConnectionName, "sslmode=disable"
+		log.Printf("📡 DSN Cloud SQL: user=%s host=/cloudsql/... dbname=%s", db.User, db.Name)
+	} else {
+		log.Printf("📡 DSN Local: user=%s host=%s port=%s dbname=%s", db.User, db.Host, db.Port, db.Name)
+	}
+
+	return fmt.Sprintf("host=%s dbname=%s user=%s password=%s %s",
+		pgValue(host),
+		pgValue(db.Name),
+		pgValue(db.User),
+		pgValue(db.Password),
+		params,
+	)
+}
+
+// pgValue quotes a DSN value so passwords may hold spaces and quotes
+func pgValue(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
+
+func (db *DatabaseConfig) Connect() (*sql.DB, error) {
+	d, err := dialect.For(db.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	dsn := db.GetDSN()
+
+	log.Printf("🔌 Conectando a %s...", db.Driver)
+
+	conn, err := sql.Open(d.SQLDriver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error abriendo conexión: %w", err)
+	}
+
+	conn.SetMaxOpenConns(db.MaxOpenConns)
+	conn.SetMaxIdleConns(min(5, db.MaxOpenConns))
+	conn.SetConnMaxLifetime(5 * time.Minute)
+
+	var pingErr error
+	for i := 0; i < 30; i++ {
+		pingErr = conn.Ping()
+		if pingErr == nil {
+			break
+		}
+		log.Printf("⏳ Esperando %s... intento %d/30", db.Driver, i+1)
+		time.Sleep(2 * time.Second)
+	}
+
+	if pingErr != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error conectando después de reintentos: %w", pingErr)
+	}
+
+	log.Printf("✅ Conexión a %s establecida", db.Driver)
+	return conn, nil
+}
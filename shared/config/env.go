@@ -0,0 +1,152 @@
+// Package config holds the configuration helpers both services load their
+// settings with: environment detection, Secret Manager access, typed env
+// variables and the settings every service shares.
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/joho/godotenv"
+)
+
+type Environment string
+
+const (
+	EnvLocal      Environment = "local"
+	EnvProduction Environment = "production"
+)
+
+func DetectEnvironment() Environment {
+	if os.Getenv("GCP_PROJECT_ID") != "" {
+		return EnvProduction
+	}
+
+	if os.Getenv("ENVIRONMENT") == "production" {
+		return EnvProduction
+	}
+
+	return EnvLocal
+}
+
+var loadDotEnv sync.Once
+
+// LoadSecret lee un secreto de Secret Manager en producción o de la variable
+// envKey (o el archivo .env) en local. Termina el proceso si no existe.
+func LoadSecret(envKey, secretName string, env Environment) string {
+	if env == EnvProduction {
+		log.Printf("🔐 Obteniendo '%s' desde Secret Manager", secretName)
+		return GetSecretFromGCP(secretName)
+	}
+
+	loadDotEnv.Do(func() {
+		if err := godotenv.Load(); err != nil {
+			log.Println("Warning: .env file not found, usando variables de entorno del sistema")
+		}
+	})
+	value := os.Getenv(envKey)
+	if value == "" {
+		log.Fatalf("❌ Variable %s no encontrada en ambiente local", envKey)
+	}
+	return value
+}
+
+func GetSecretFromGCP(secretName string) string {
+	projectID := os.Getenv("GCP_PROJECT_ID")
+	if projectID == "" {
+		log.Fatal("❌ GCP_PROJECT_ID no configurado")
+	}
+
+	ctx := context.Background()
+	client, err := secretmanager.NewClient(ctx)
+	if err != nil {
+		log.Fatalf("❌ Error creando cliente Secret Manager: %v", err)
+	}
+	defer client.Close()
+
+	name := fmt.Sprintf("projects/%s/secrets/%s/versions/latest", projectID, secretName)
+	req := &secretmanagerpb.AccessSecretVersionRequest{Name: name}
+
+	result, err := client.AccessSecretVersion(ctx, req)
+	if err != nil {
+		log.Fatalf("❌ Error accediendo al secreto %s: %v", secretName, err)
+	}
+
+	log.Printf("✅ Secreto '%s' obtenido correctamente", secretName)
+	return string(result.Payload.Data)
+}
+
+func GetEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func GetEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("❌ Variable %s debe ser true o false: %v", key, err)
+	}
+	return parsed
+}
+
+func GetEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("❌ Variable %s debe ser un entero: %v", key, err)
+	}
+	return parsed
+}
+
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("❌ Variable %s debe ser una duración (ej. 10m): %v", key, err)
+	}
+	return parsed
+}
+
+func GetEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Fatalf("❌ Variable %s debe ser un número: %v", key, err)
+	}
+	return parsed
+}
+
+// SplitList parses a comma separated env value, ignoring blanks
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,166 @@
+// Package dialect holds the few SQL differences between the databases the
+// services can run on. Queries are written with "?" placeholders and pass
+// through Rebind; a Dialect supplies the clauses that have no common form.
+package dialect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	mysql "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Driver names, as set in DB_DRIVER
+const (
+	MySQL    = "mysql"
+	SQLite   = "sqlite"
+	Postgres = "postgres"
+)
+
+type Dialect struct {
+	// Driver is the DB_DRIVER name and SQLDriver the database/sql one
+	Driver    string
+	SQLDriver string
+
+	// ForUpdate locks the rows a SELECT reads until the transaction ends,
+	// and SkipLocked does too but skips rows another transaction holds.
+	// SQLite has no row locks; its write transactions are already exclusive,
+	// so both are empty there.
+	ForUpdate  string
+	SkipLocked string
+
+	numbered    bool // $1, $2... placeholders
+	returning   bool // No LastInsertId; ids come back with RETURNING
+	isDuplicate func(err error) bool
+	upsert      func(conflict []string, update []string) string
+}
+
+// For returns the dialect of a driver name
+func For(driver string) (Dialect, error) {
+	switch driver {
+	case MySQL:
+		return mysqlDialect, nil
+	case SQLite:
+		return sqliteDialect, nil
+	case Postgres:
+		return postgresDialect, nil
+	default:
+		return Dialect{}, fmt.Errorf("unsupported database driver %q", driver)
+	}
+}
+
+// Rebind rewrites the "?" placeholders of query into the dialect's own
+func (d Dialect) Rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Querier is implemented by *sql.DB, *sql.Tx and *sql.Conn
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// InsertID runs an INSERT into a table with an "id" key and returns the id
+// generated for the new row
+func (d Dialect) InsertID(ctx context.Context, q Querier, query string, args ...interface{}) (int64, error) {
+	if d.returning {
+		var id int64
+		err := q.QueryRowContext(ctx, d.Rebind(query)+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	res, err := q.ExecContext(ctx, d.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// IsDuplicate reports whether err is a unique key violation
+func (d Dialect) IsDuplicate(err error) bool {
+	return err != nil && d.isDuplicate(err)
+}
+
+// Upsert returns the clause that follows an INSERT so a row clashing on the
+// conflict columns (a unique key) gets the update columns overwritten with
+// the inserted values instead
+func (d Dialect) Upsert(conflict []string, update ...string) string {
+	return d.upsert(conflict, update)
+}
+
+var mysqlDialect = Dialect{
+	Driver:     MySQL,
+	SQLDriver:  "mysql",
+	ForUpdate:  "FOR UPDATE",
+	SkipLocked: "FOR UPDATE SKIP LOCKED",
+	isDuplicate: func(err error) bool {
+		var me *mysql.MySQLError
+		return errors.As(err, &me) && me.Number == 1062
+	},
+	upsert: func(_ []string, update []string) string {
+		sets := make([]string, len(update))
+		for i, col := range update {
+			sets[i] = fmt.Sprintf("%s = VALUES(%s)", col, col)
+		}
+		return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+	},
+}
+
+var sqliteDialect = Dialect{
+	Driver:    SQLite,
+	SQLDriver: "sqlite",
+	isDuplicate: func(err error) bool {
+		var se *sqlite.Error
+		return errors.As(err, &se) &&
+			(se.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || se.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY)
+	},
+	upsert: onConflict,
+}
+
+var postgresDialect = Dialect{
+	Driver:     Postgres,
+	SQLDriver:  "pgx",
+	ForUpdate:  "FOR UPDATE",
+	SkipLocked: "FOR UPDATE SKIP LOCKED",
+	numbered:   true,
+	returning:  true,
+	isDuplicate: func(err error) bool {
+		var pe *pgconn.PgError
+		return errors.As(err, &pe) && pe.Code == "23505" // unique_violation
+	},
+	upsert: onConflict,
+}
+
+// onConflict is the SQLite and PostgreSQL upsert
+func onConflict(conflict []string, update []string) string {
+	sets := make([]string, len(update))
+	for i, col := range update {
+		sets[i] = fmt.Sprintf("%s = excluded.%s", col, col)
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(conflict, ", "), strings.Join(sets, ", "))
+}
+
+// NullString stores empty optional text columns as NULL
+func NullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
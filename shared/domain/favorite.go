@@ -0,0 +1,254 @@
+// Package domain holds the favorite conversion model and the rules for
+// validating it, shared by the api (which stores favorites) and the worker
+// (which evaluates them).
+package domain
+
+import (
+	"database/sql"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RatesBase is the base every rate table is fetched in, the only one allowed
+// by the exchangeratesapi.io free plan. Other pairs are crossed through it.
+const RatesBase = "EUR"
+
+// Directions a favorite threshold can be watched in. Crossing directions
+// only fire when the rate moves past the threshold between two checks.
+const (
+	DirectionAbove       = "above"
+	DirectionBelow       = "below"
+	DirectionCrossesUp   = "crosses_up"
+	DirectionCrossesDown = "crosses_down"
+)
+
+var validDirections = map[string]bool{
+	DirectionAbove:       true,
+	DirectionBelow:       true,
+	DirectionCrossesUp:   true,
+	DirectionCrossesDown: true,
+}
+
+// Rule types. For pct_change the threshold is a percentage and for
+// volatility a standard deviation, both measured over the favorite's window
+// from the stored rate history.
+const (
+	RuleThreshold  = "threshold"
+	RulePctChange  = "pct_change"
+	RuleVolatility = "volatility"
+)
+
+// MaxRuleWindow bounds how much rate history a rule may look at
+const MaxRuleWindow = 90 * 24 * time.Hour
+
+// ValidateRule checks the rule-specific fields of a favorite and returns the
+// parsed window (zero for threshold rules)
+func ValidateRule(rule, direction, window string, threshold float64) (time.Duration, error) {
+	switch rule {
+	case RuleThreshold:
+		if window != "" {
+			return 0, fmt.Errorf("window only applies to pct_change and volatility rules")
+		}
+		return 0, nil
+	case RulePctChange, RuleVolatility:
+	default:
+		return 0, fmt.Errorf("rule must be threshold, pct_change or volatility")
+	}
+
+	if direction != DirectionAbove && direction != DirectionBelow {
+		return 0, fmt.Errorf("%s rules only support the above and below directions", rule)
+	}
+	if threshold <= 0 {
+		return 0, fmt.Errorf("threshold must be positive for %s rules", rule)
+	}
+
+	d, err := ParseWindow(window)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 || d > MaxRuleWindow {
+		return 0, fmt.Errorf("window must be between 1s and %s", FormatWindow(MaxRuleWindow))
+	}
+	return d, nil
+}
+
+// ParseWindow accepts Go durations ("24h") and whole days ("7d")
+func ParseWindow(window string) (time.Duration, error) {
+	if window == "" {
+		return 0, fmt.Errorf("window is required")
+	}
+	if days, ok := strings.CutSuffix(window, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid window %q", window)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window %q", window)
+	}
+	return d, nil
+}
+
+func FormatWindow(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+// Channels a favorite can be notified through. Email goes to the favorite's
+// email; the others post to the favorite's target URL.
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelSlack   = "slack"
+	ChannelTeams   = "teams"
+)
+
+var validChannels = map[string]bool{
+	ChannelEmail:   true,
+	ChannelWebhook: true,
+	ChannelSlack:   true,
+	ChannelTeams:   true,
+}
+
+// MaxTargetLength matches the target column
+const MaxTargetLength = 2048
+
+// chatWebhookHosts are the hosts Slack and Teams incoming webhooks live on;
+// a leading dot matches any subdomain. Unlike the webhook channel, chat
+// messages are unsigned and always enabled, so they may not go elsewhere.
+var chatWebhookHosts = map[string][]string{
+	ChannelSlack: {"hooks.slack.com"},
+	ChannelTeams: {
+		".webhook.office.com", "outlook.office.com", "outlook.office365.com", // Connectors
+		".logic.azure.com", ".api.powerplatform.com", // Workflows
+	},
+}
+
+// isChatWebhookHost reports whether host serves channel's incoming webhooks
+func isChatWebhookHost(channel, host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range chatWebhookHosts[channel] {
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// nonPublicPrefixes are the ranges netip has no predicate for that are
+// still not reachable from the internet: "this network" and carrier-grade
+// NAT, where some clouds put their metadata servers
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// IsPublicAddr reports whether addr is a unicast address on the internet,
+// not loopback, link-local (like the metadata server), private or reserved
+// for the local network. Webhooks may only be delivered to public ones.
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// isLocalHostname reports whether host names a machine on the local network
+// rather than on the internet
+func isLocalHostname(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return !IsPublicAddr(addr)
+	}
+	return host == "localhost" || !strings.Contains(host, ".") ||
+		strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".local") || strings.HasSuffix(host, ".internal")
+}
+
+// ValidateChannel checks that target suits channel: none for email, an
+// absolute URL on an internet host for webhooks and an https URL on the Slack or Teams webhook
+// hosts for those
+func ValidateChannel(channel, target string) error {
+	if !validChannels[channel] {
+		return fmt.Errorf("channel must be email, webhook, slack or teams")
+	}
+	if channel == ChannelEmail {
+		if target != "" {
+			return fmt.Errorf("target does not apply to the email channel")
+		}
+		return nil
+	}
+
+	if target == "" {
+		return fmt.Errorf("target is required for the %s channel", channel)
+	}
+	if len(target) > MaxTargetLength {
+		return fmt.Errorf("target must be at most %d characters", MaxTargetLength)
+	}
+	u, err := url.Parse(target)
+	if channel == ChannelWebhook {
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("target must be an absolute http or https URL")
+		}
+		// The worker also refuses to connect to non-public addresses, which
+		// covers names resolving to them
+		if isLocalHostname(u.Hostname()) {
+			return fmt.Errorf("target must be on a public host, not loopback, link-local or a private network")
+		}
+		return nil
+	}
+	if err != nil || u.Host == "" || u.Scheme != "https" {
+		return fmt.Errorf("target must be an https %s webhook URL", channel)
+	}
+	if !isChatWebhookHost(channel, u.Hostname()) || (u.Port() != "" && u.Port() != "443") {
+		var hosts []string
+		for _, host := range chatWebhookHosts[channel] {
+			if strings.HasPrefix(host, ".") {
+				host = "*" + host
+			}
+			hosts = append(hosts, host)
+		}
+		return fmt.Errorf("target must be a %s incoming webhook URL on %s", channel, strings.Join(hosts, ", "))
+	}
+	return nil
+}
+
+// FavoriteConversion is a stored row of favorite_conversions
+type FavoriteConversion struct {
+	ID                  int64
+	Email               string
+	CurrencyOrigin      string
+	CurrencyDestination string
+	Threshold           float64
+	Direction           string
+	LastRate            sql.NullFloat64 // Rate seen on the previous check
+	RuleType            string
+	Window              time.Duration
+	Armed               bool
+	LastNotifiedAt      sql.NullTime
+	LastNotifiedRate    sql.NullFloat64
+	Channel             string
+	Target              string // Empty for the email channel
+	CreatedAt           time.Time
+}
+
+// ValidDirection reports whether direction is one of the known directions
+func ValidDirection(direction string) bool {
+	return validDirections[direction]
+}
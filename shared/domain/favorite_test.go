@@ -0,0 +1,72 @@
+package domain
+
+import "testing"
+
+func TestValidateChannelWebhookTargets(t *testing.T) {
+	tests := []struct {
+		target string
+		valid  bool
+	}{
+		{"https://example.com/hooks/alerts", true},
+		{"http://203.0.113.10:8080/hook", true},
+		{"https://[2001:db8::1]/hook", true},
+		{"ftp://example.com/hook", false},
+		{"/relative/hook", false},
+		{"http://localhost:8080/hook", false},
+		{"http://api.localhost/hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://[::ffff:127.0.0.1]/hook", false},
+		{"http://169.254.169.254/computeMetadata/v1/", false},
+		{"http://metadata.google.internal/computeMetadata/v1/", false},
+		{"http://metadata/computeMetadata/v1/", false},
+		{"http://10.0.0.5/hook", false},
+		{"http://172.16.3.4/hook", false},
+		{"http://192.168.1.1/hook", false},
+		{"http://100.100.100.200/latest/meta-data/", false},
+		{"http://0.0.0.0:8080/hook", false},
+		{"http://[fd00::1]/hook", false},
+		{"http://[fe80::1]/hook", false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateChannel(ChannelWebhook, tt.target)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateChannel(webhook, %q) = %v, want valid %v", tt.target, err, tt.valid)
+		}
+	}
+}
+
+func TestValidateChannelChatTargets(t *testing.T) {
+	tests := []struct {
+		channel string
+		target  string
+		valid   bool
+	}{
+		{ChannelSlack, "https://hooks.slack.com/services/T000/B000/XXXX", true},
+		{ChannelSlack, "https://HOOKS.slack.com:443/services/T000/B000/XXXX", true},
+		{ChannelSlack, "http://hooks.slack.com/services/T000/B000/XXXX", false},
+		{ChannelSlack, "https://hooks.slack.com:8443/services/T000/B000/XXXX", false},
+		{ChannelSlack, "https://example.com/services/T000/B000/XXXX", false},
+		{ChannelSlack, "https://hooks.slack.com.evil.example/services/T000", false},
+		{ChannelSlack, "https://hooks.slack.com@evil.example/services/T000", false},
+		{ChannelSlack, "https://evil.example/?next=hooks.slack.com", false},
+		{ChannelSlack, "https://outlook.office.com/webhook/abc", false},
+		{ChannelSlack, "", false},
+		{ChannelTeams, "https://contoso.webhook.office.com/webhookb2/abc", true},
+		{ChannelTeams, "https://outlook.office.com/webhook/abc", true},
+		{ChannelTeams, "https://prod-01.westus.logic.azure.com/workflows/abc", true},
+		{ChannelTeams, "https://webhook.office.com/webhookb2/abc", false},
+		{ChannelTeams, "https://evilwebhook.office.com/webhookb2/abc", false},
+		{ChannelTeams, "http://contoso.webhook.office.com/webhookb2/abc", false},
+		{ChannelTeams, "https://contoso.webhook.office.com@evil.example/webhookb2/abc", false},
+		{ChannelTeams, "https://hooks.slack.com/services/T000/B000/XXXX", false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateChannel(tt.channel, tt.target)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateChannel(%s, %q) = %v, want valid %v", tt.channel, tt.target, err, tt.valid)
+		}
+	}
+}
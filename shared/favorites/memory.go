@@ -0,0 +1,165 @@
+package favorites
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+)
+
+// MemoryRepository keeps favorites in memory, for tests. It enforces the
+// same unique rule as the favorite_conversions table.
+type MemoryRepository struct {
+	mu        sync.Mutex
+	nextID    int64
+	favorites map[int64]domain.FavoriteConversion
+}
+
+func NewMemory() *MemoryRepository {
+	return &MemoryRepository{favorites: map[int64]domain.FavoriteConversion{}}
+}
+
+// sameWatch reports whether a and b clash on the unique_watch_rule key
+func sameWatch(a, b domain.FavoriteConversion) bool {
+	return a.Email == b.Email && a.CurrencyOrigin == b.CurrencyOrigin &&
+		a.CurrencyDestination == b.CurrencyDestination && a.Direction == b.Direction && a.RuleType == b.RuleType
+}
+
+func (r *MemoryRepository) clashes(fav domain.FavoriteConversion) bool {
+	for id, other := range r.favorites {
+		if id != fav.ID && sameWatch(fav, other) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, fav *domain.FavoriteConversion) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *fav
+	stored.ID = r.nextID + 1
+	if r.clashes(stored) {
+		return 0, ErrAlreadyExists
+	}
+
+	if stored.Channel == "" {
+		stored.Channel = domain.ChannelEmail
+	}
+	stored.Armed = true
+	stored.CreatedAt = time.Now().UTC()
+	r.nextID = stored.ID
+	r.favorites[stored.ID] = stored
+	return stored.ID, nil
+}
+
+func (r *MemoryRepository) Get(ctx context.Context, id int64) (*domain.FavoriteConversion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fav, ok := r.favorites[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &fav, nil
+}
+
+func (r *MemoryRepository) ListByEmail(ctx context.Context, email string) ([]domain.FavoriteConversion, error) {
+	return r.list(func(fav domain.FavoriteConversion) bool { return fav.Email == email }), nil
+}
+
+func (r *MemoryRepository) ListAll(ctx context.Context) ([]domain.FavoriteConversion, error) {
+	return r.list(func(domain.FavoriteConversion) bool { return true }), nil
+}
+
+func (r *MemoryRepository) list(keep func(fav domain.FavoriteConversion) bool) []domain.FavoriteConversion {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	favorites := []domain.FavoriteConversion{}
+	for _, fav := range r.favorites {
+		if keep(fav) {
+			favorites = append(favorites, fav)
+		}
+	}
+	sort.Slice(favorites, func(i, j int) bool { return favorites[i].ID < favorites[j].ID })
+	return favorites
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, id int64, apply func(fav *domain.FavoriteConversion) error) (*domain.FavoriteConversion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.favorites[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	fav := stored
+	if err := apply(&fav); err != nil {
+		return nil, err
+	}
+	forgetOldPair(&stored, &fav)
+
+	// Only the fields the SQL repository writes change
+	stored.Threshold = fav.Threshold
+	stored.CurrencyDestination = fav.CurrencyDestination
+	stored.Channel = fav.Channel
+	stored.Target = fav.Target
+	stored.Armed = fav.Armed
+	stored.LastRate = fav.LastRate
+	stored.LastNotifiedAt = fav.LastNotifiedAt
+	stored.LastNotifiedRate = fav.LastNotifiedRate
+	if r.clashes(stored) {
+		return nil, ErrAlreadyExists
+	}
+
+	r.favorites[id] = stored
+	return &fav, nil
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.favorites[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.favorites, id)
+	return nil
+}
+
+func (r *MemoryRepository) DeleteAll(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := int64(len(r.favorites))
+	r.favorites = map[int64]domain.FavoriteConversion{}
+	return n, nil
+}
+
+func (r *MemoryRepository) SetLastRate(ctx context.Context, id int64, rate float64) error {
+	return r.modify(id, func(fav *domain.FavoriteConversion) {
+		fav.LastRate.Float64, fav.LastRate.Valid = rate, true
+	})
+}
+
+func (r *MemoryRepository) Rearm(ctx context.Context, id int64) error {
+	return r.modify(id, func(fav *domain.FavoriteConversion) { fav.Armed = true })
+}
+
+// modify changes a stored favorite in place; like an UPDATE, an unknown id
+// is not an error
+func (r *MemoryRepository) modify(id int64, change func(fav *domain.FavoriteConversion)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if fav, ok := r.favorites[id]; ok {
+		change(&fav)
+		r.favorites[id] = fav
+	}
+	return nil
+}
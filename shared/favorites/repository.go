@@ -0,0 +1,56 @@
+// Package favorites stores favorite conversions. The api manages them and
+// the worker reads them and keeps their alert state, both through
+// Repository.
+package favorites
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+)
+
+var (
+	ErrNotFound      = errors.New("favorite not found")
+	ErrAlreadyExists = errors.New("favorite for this email and pair already exists")
+)
+
+// Repository is where favorites live. Implementations return ErrNotFound
+// for unknown ids and ErrAlreadyExists when a favorite would repeat the
+// email, pair, direction and rule of another one.
+type Repository interface {
+	// Create stores a new, armed favorite and returns its id
+	Create(ctx context.Context, fav *domain.FavoriteConversion) (int64, error)
+	Get(ctx context.Context, id int64) (*domain.FavoriteConversion, error)
+	ListByEmail(ctx context.Context, email string) ([]domain.FavoriteConversion, error)
+	ListAll(ctx context.Context) ([]domain.FavoriteConversion, error)
+
+	// Update loads the favorite, lets apply change it and stores the
+	// threshold, destination, channel, target and alert state (armed, last
+	// rate and last notification) it leaves. Changing the pair also forgets
+	// the last rate and notification, which belonged to the old one.
+	// Nothing is stored if apply fails; its error is returned as is.
+	Update(ctx context.Context, id int64, apply func(fav *domain.FavoriteConversion) error) (*domain.FavoriteConversion, error)
+
+	Delete(ctx context.Context, id int64) error
+	DeleteAll(ctx context.Context) (int64, error)
+
+	// SetLastRate remembers the rate seen on a check, so the next one can
+	// detect crossings
+	SetLastRate(ctx context.Context, id int64, rate float64) error
+
+	// Rearm lets a favorite notify again
+	Rearm(ctx context.Context, id int64) error
+}
+
+// forgetOldPair clears the alert state fav kept from before an update that
+// changed its pair, so crossings are not detected between two pairs
+func forgetOldPair(before, fav *domain.FavoriteConversion) {
+	if fav.CurrencyOrigin == before.CurrencyOrigin && fav.CurrencyDestination == before.CurrencyDestination {
+		return
+	}
+	fav.LastRate = sql.NullFloat64{}
+	fav.LastNotifiedAt = sql.NullTime{}
+	fav.LastNotifiedRate = sql.NullFloat64{}
+}
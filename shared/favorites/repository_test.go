@@ -0,0 +1,192 @@
+package favorites_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/config"
+	"github.com/joy-currency-conversion-GCP/shared/dialect"
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+	"github.com/joy-currency-conversion-GCP/shared/favorites"
+	"github.com/joy-currency-conversion-GCP/shared/schema"
+)
+
+// repositories runs test against every backend that works without a server
+func repositories(t *testing.T, test func(t *testing.T, repo favorites.Repository)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, favorites.NewMemory())
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		cfg := config.DatabaseConfig{Driver: dialect.SQLite, Path: filepath.Join(t.TempDir(), "favorites.db")}
+		db, err := cfg.Connect()
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		if err := schema.Migrate(context.Background(), db, dialect.SQLite, time.Minute); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+		d, err := dialect.For(dialect.SQLite)
+		if err != nil {
+			t.Fatal(err)
+		}
+		test(t, favorites.NewSQL(db, d))
+	})
+}
+
+func newFavorite(email, destination string) *domain.FavoriteConversion {
+	return &domain.FavoriteConversion{
+		Email:               email,
+		CurrencyOrigin:      "EUR",
+		CurrencyDestination: destination,
+		Threshold:           1.1,
+		Direction:           domain.DirectionAbove,
+		RuleType:            domain.RuleThreshold,
+		Channel:             domain.ChannelEmail,
+	}
+}
+
+func mustCreate(t *testing.T, repo favorites.Repository, fav *domain.FavoriteConversion) int64 {
+	t.Helper()
+	id, err := repo.Create(context.Background(), fav)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	return id
+}
+
+func TestCreateRejectsDuplicates(t *testing.T) {
+	repositories(t, func(t *testing.T, repo favorites.Repository) {
+		ctx := context.Background()
+		id := mustCreate(t, repo, newFavorite("ana@example.com", "USD"))
+
+		if _, err := repo.Create(ctx, newFavorite("ana@example.com", "USD")); !errors.Is(err, favorites.ErrAlreadyExists) {
+			t.Fatalf("duplicate create: got %v, want ErrAlreadyExists", err)
+		}
+
+		below := newFavorite("ana@example.com", "USD")
+		below.Direction = domain.DirectionBelow
+		mustCreate(t, repo, below)
+
+		fav, err := repo.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if !fav.Armed {
+			t.Error("new favorite is not armed")
+		}
+	})
+}
+
+func TestUnknownIDIsNotFound(t *testing.T) {
+	repositories(t, func(t *testing.T, repo favorites.Repository) {
+		ctx := context.Background()
+
+		if _, err := repo.Get(ctx, 42); !errors.Is(err, favorites.ErrNotFound) {
+			t.Errorf("get: got %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, 42); !errors.Is(err, favorites.ErrNotFound) {
+			t.Errorf("delete: got %v, want ErrNotFound", err)
+		}
+		_, err := repo.Update(ctx, 42, func(fav *domain.FavoriteConversion) error { return nil })
+		if !errors.Is(err, favorites.ErrNotFound) {
+			t.Errorf("update: got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	repositories(t, func(t *testing.T, repo favorites.Repository) {
+		ctx := context.Background()
+		id := mustCreate(t, repo, newFavorite("ana@example.com", "USD"))
+		mustCreate(t, repo, newFavorite("ana@example.com", "GBP"))
+
+		t.Run("stores the changes", func(t *testing.T) {
+			_, err := repo.Update(ctx, id, func(fav *domain.FavoriteConversion) error {
+				fav.Threshold = 1.2
+				fav.Armed = false
+				fav.LastNotifiedRate = sql.NullFloat64{Float64: 1.15, Valid: true}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("update: %v", err)
+			}
+
+			fav, err := repo.Get(ctx, id)
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			if fav.Threshold != 1.2 || fav.Armed || fav.LastNotifiedRate.Float64 != 1.15 {
+				t.Errorf("got threshold %v, armed %v, last notified rate %v", fav.Threshold, fav.Armed, fav.LastNotifiedRate)
+			}
+		})
+
+		t.Run("stores nothing if apply fails", func(t *testing.T) {
+			failure := errors.New("invalid threshold")
+			_, err := repo.Update(ctx, id, func(fav *domain.FavoriteConversion) error {
+				fav.Threshold = 9
+				return failure
+			})
+			if err != failure {
+				t.Fatalf("got %v, want the apply error as is", err)
+			}
+
+			fav, err := repo.Get(ctx, id)
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			if fav.Threshold != 1.2 {
+				t.Errorf("threshold changed to %v", fav.Threshold)
+			}
+		})
+
+		t.Run("rejects duplicates", func(t *testing.T) {
+			_, err := repo.Update(ctx, id, func(fav *domain.FavoriteConversion) error {
+				fav.CurrencyDestination = "GBP"
+				return nil
+			})
+			if !errors.Is(err, favorites.ErrAlreadyExists) {
+				t.Fatalf("got %v, want ErrAlreadyExists", err)
+			}
+
+			fav, err := repo.Get(ctx, id)
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			if fav.CurrencyDestination != "USD" {
+				t.Errorf("destination changed to %s", fav.CurrencyDestination)
+			}
+		})
+
+		t.Run("forgets the alert state of the old pair", func(t *testing.T) {
+			if err := repo.SetLastRate(ctx, id, 1.08); err != nil {
+				t.Fatalf("set last rate: %v", err)
+			}
+
+			updated, err := repo.Update(ctx, id, func(fav *domain.FavoriteConversion) error {
+				fav.CurrencyDestination = "JPY"
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("update: %v", err)
+			}
+			if updated.LastRate.Valid || updated.LastNotifiedRate.Valid || updated.LastNotifiedAt.Valid {
+				t.Errorf("returned favorite kept the old pair's state: %+v", updated)
+			}
+
+			fav, err := repo.Get(ctx, id)
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+			if fav.CurrencyDestination != "JPY" || fav.LastRate.Valid || fav.LastNotifiedRate.Valid || fav.LastNotifiedAt.Valid {
+				t.Errorf("stored favorite kept the old pair's state: %+v", fav)
+			}
+		})
+	})
+}
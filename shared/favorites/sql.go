@@ -0,0 +1,185 @@
+package favorites
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/dialect"
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+)
+
+// SQLRepository keeps favorites in the favorite_conversions table
+type SQLRepository struct {
+	db      *sql.DB
+	dialect dialect.Dialect
+}
+
+func NewSQL(db *sql.DB, d dialect.Dialect) *SQLRepository {
+	return &SQLRepository{db: db, dialect: d}
+}
+
+const columns = `id, email, currency_origin, currency_destination, threshold, direction, last_rate, rule_type,
+	window_seconds, armed, last_notified_at, last_notified_rate, channel, target, created_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scan(row rowScanner) (*domain.FavoriteConversion, error) {
+	var fav domain.FavoriteConversion
+	var windowSeconds sql.NullInt64
+	var target sql.NullString
+	err := row.Scan(&fav.ID, &fav.Email, &fav.CurrencyOrigin, &fav.CurrencyDestination, &fav.Threshold,
+		&fav.Direction, &fav.LastRate, &fav.RuleType, &windowSeconds, &fav.Armed, &fav.LastNotifiedAt,
+		&fav.LastNotifiedRate, &fav.Channel, &target, &fav.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	fav.Window = time.Duration(windowSeconds.Int64) * time.Second
+	fav.Target = target.String
+	return &fav, nil
+}
+
+func (r *SQLRepository) Create(ctx context.Context, fav *domain.FavoriteConversion) (int64, error) {
+	id, err := r.dialect.InsertID(ctx, r.db,
+		`INSERT INTO favorite_conversions (email, currency_origin, currency_destination, threshold, direction, rule_type, window_seconds, channel, target) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		fav.Email, fav.CurrencyOrigin, fav.CurrencyDestination, fav.Threshold, fav.Direction, fav.RuleType, windowSeconds(fav.Window),
+		fav.Channel, dialect.NullString(fav.Target),
+	)
+	if err != nil {
+		if r.dialect.IsDuplicate(err) {
+			return 0, ErrAlreadyExists
+		}
+		return 0, fmt.Errorf("failed to insert favorite conversion: %w", err)
+	}
+	return id, nil
+}
+
+func (r *SQLRepository) Get(ctx context.Context, id int64) (*domain.FavoriteConversion, error) {
+	fav, err := scan(r.db.QueryRowContext(ctx, r.dialect.Rebind(`SELECT `+columns+` FROM favorite_conversions WHERE id = ?`), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get favorite conversion: %w", err)
+	}
+	return fav, nil
+}
+
+func (r *SQLRepository) ListByEmail(ctx context.Context, email string) ([]domain.FavoriteConversion, error) {
+	return r.list(ctx, `SELECT `+columns+` FROM favorite_conversions WHERE email = ? ORDER BY id`, email)
+}
+
+func (r *SQLRepository) ListAll(ctx context.Context) ([]domain.FavoriteConversion, error) {
+	return r.list(ctx, `SELECT `+columns+` FROM favorite_conversions ORDER BY id`)
+}
+
+func (r *SQLRepository) list(ctx context.Context, query string, args ...interface{}) ([]domain.FavoriteConversion, error) {
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list favorite conversions: %w", err)
+	}
+	defer rows.Close()
+
+	favorites := []domain.FavoriteConversion{}
+	for rows.Next() {
+		fav, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan favorite conversion: %w", err)
+		}
+		favorites = append(favorites, *fav)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list favorite conversions: %w", err)
+	}
+	return favorites, nil
+}
+
+func (r *SQLRepository) Update(ctx context.Context, id int64, apply func(fav *domain.FavoriteConversion) error) (*domain.FavoriteConversion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	fav, err := scan(tx.QueryRowContext(ctx,
+		r.dialect.Rebind(`SELECT `+columns+` FROM favorite_conversions WHERE id = ? `+r.dialect.ForUpdate), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get favorite conversion: %w", err)
+	}
+
+	before := *fav
+	if err := apply(fav); err != nil {
+		return nil, err
+	}
+	forgetOldPair(&before, fav)
+
+	_, err = tx.ExecContext(ctx,
+		r.dialect.Rebind(`UPDATE favorite_conversions SET threshold = ?, currency_destination = ?, channel = ?, target = ?, armed = ?,
+			last_rate = ?, last_notified_at = ?, last_notified_rate = ? WHERE id = ?`),
+		fav.Threshold, fav.CurrencyDestination, fav.Channel, dialect.NullString(fav.Target), fav.Armed,
+		fav.LastRate, fav.LastNotifiedAt, fav.LastNotifiedRate, id,
+	)
+	if err != nil {
+		if r.dialect.IsDuplicate(err) {
+			return nil, ErrAlreadyExists
+		}
+		return nil, fmt.Errorf("failed to update favorite conversion: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit favorite update: %w", err)
+	}
+	return fav, nil
+}
+
+func (r *SQLRepository) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, r.dialect.Rebind(`DELETE FROM favorite_conversions WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete favorite conversion: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLRepository) DeleteAll(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM favorite_conversions`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete favorite conversions: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (r *SQLRepository) SetLastRate(ctx context.Context, id int64, rate float64) error {
+	_, err := r.db.ExecContext(ctx, r.dialect.Rebind(`UPDATE favorite_conversions SET last_rate = ? WHERE id = ?`), rate, id)
+	if err != nil {
+		return fmt.Errorf("failed to update last rate: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLRepository) Rearm(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, r.dialect.Rebind(`UPDATE favorite_conversions SET armed = TRUE WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("failed to re-arm favorite: %w", err)
+	}
+	return nil
+}
+
+// windowSeconds stores threshold rules, which have no window, as NULL
+func windowSeconds(window time.Duration) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(window / time.Second), Valid: window > 0}
+}
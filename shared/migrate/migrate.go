@@ -0,0 +1,505 @@
+// Package migrate applies ordered, versioned SQL migrations and records
+// them in a schema_migrations table. Migrations run on one connection that
+// holds an advisory lock, so the api and worker never migrate at once.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is one schema version. Up and Down may hold several
+// statements separated by ";" (see SplitStatements).
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration and when it was applied, if it was
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load reads "<version>_<name>.up.sql" and "<version>_<name>.down.sql"
+// files from the root of fsys. Every version needs an up file; down files
+// are optional.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		m := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(m[1])
+
+		body, err := fs.ReadFile(fsys, path.Clean(entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", entry.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if strings.TrimSpace(mig.Up) == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Locker serializes migrations across processes. Lock and Unlock run on the
+// connection the migrations use.
+type Locker interface {
+	Lock(ctx context.Context, conn *sql.Conn) error
+	Unlock(ctx context.Context, conn *sql.Conn) error
+}
+
+// MySQLLock is a MySQL named lock (GET_LOCK), released when the connection
+// closes even if the process dies
+type MySQLLock struct {
+	Name    string
+	Timeout time.Duration
+}
+
+func (l MySQLLock) Lock(ctx context.Context, conn *sql.Conn) error {
+	var got sql.NullInt64
+	err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, ?)`, l.Name, int(l.Timeout.Seconds())).Scan(&got)
+	if err != nil {
+		return fmt.Errorf("error acquiring migration lock: %w", err)
+	}
+	if got.Int64 != 1 {
+		return fmt.Errorf("timed out after %s waiting for migration lock %q", l.Timeout, l.Name)
+	}
+	return nil
+}
+
+func (l MySQLLock) Unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT RELEASE_LOCK(?)`, l.Name)
+	return err
+}
+
+// PostgresLock is a PostgreSQL session advisory lock keyed by the hash of
+// Name, released when the connection closes even if the process dies
+type PostgresLock struct {
+	Name    string
+	Timeout time.Duration
+}
+
+func (l PostgresLock) Lock(ctx context.Context, conn *sql.Conn) error {
+	deadline := time.Now().Add(l.Timeout)
+	for {
+		var got bool
+		err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, l.Name).Scan(&got)
+		if err != nil {
+			return fmt.Errorf("error acquiring migration lock: %w", err)
+		}
+		if got {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for migration lock %q", l.Timeout, l.Name)
+		}
+
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (l PostgresLock) Unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext($1))`, l.Name)
+	return err
+}
+
+// SQLiteLock takes SQLite's database-wide write lock with BEGIN IMMEDIATE
+// and holds it until Unlock commits; migrations run inside that transaction.
+// SQLite drops the lock and rolls back if the process dies, so a crash
+// never leaves the database locked.
+type SQLiteLock struct {
+	Timeout time.Duration
+}
+
+func (l SQLiteLock) Lock(ctx context.Context, conn *sql.Conn) error {
+	// SQLite waits for the lock itself, for up to the busy timeout
+	var busyTimeout int
+	if err := conn.QueryRowContext(ctx, `PRAGMA busy_timeout`).Scan(&busyTimeout); err != nil {
+		return fmt.Errorf("error reading sqlite busy timeout: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf(`PRAGMA busy_timeout = %d`, l.Timeout.Milliseconds())); err != nil {
+		return fmt.Errorf("error setting sqlite busy timeout: %w", err)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), fmt.Sprintf(`PRAGMA busy_timeout = %d`, busyTimeout))
+
+	if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		return fmt.Errorf("error acquiring the sqlite write lock (waited up to %s): %w", l.Timeout, err)
+	}
+	return nil
+}
+
+// Unlock commits the migrations that ran, releasing the lock
+func (l SQLiteLock) Unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `COMMIT`)
+	return err
+}
+
+func (SQLiteLock) inTransaction() {}
+
+// txLocker is a Locker that holds its lock as a transaction on the
+// connection, so each migration runs in a savepoint of it
+type txLocker interface {
+	Locker
+	inTransaction()
+}
+
+// Migrator applies a set of migrations to a database
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	locker     Locker
+
+	// Adopt, if set, runs under the lock before anything is applied to a
+	// database without recorded migrations, e.g. to bring a schema created
+	// before migrations existed up to the baseline
+	Adopt func(ctx context.Context, conn *sql.Conn) error
+
+	// Bind, if set, rewrites the "?" placeholders of the queries that record
+	// migrations, for databases that number them
+	Bind func(query string) string
+}
+
+func New(db *sql.DB, migrations []Migration, locker Locker) *Migrator {
+	return &Migrator{db: db, migrations: migrations, locker: locker}
+}
+
+const createTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+  version BIGINT NOT NULL PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// withLock runs fn on a dedicated connection holding the migration lock,
+// with schema_migrations in place
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, applied map[int]time.Time) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := m.locker.Lock(ctx, conn); err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := m.locker.Unlock(context.WithoutCancel(ctx), conn); unlockErr != nil && err == nil {
+			err = fmt.Errorf("error releasing migration lock: %w", unlockErr)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return fmt.Errorf("error creating schema_migrations: %w", err)
+	}
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(conn, applied)
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("error reading schema_migrations: %w", err)
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// Up applies every pending migration in order and returns how many ran
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.To(ctx, -1)
+}
+
+// To migrates up or down to target, the last version that should remain
+// applied. A negative target means the latest version and 0 reverts them
+// all; any other target must be a known version.
+func (m *Migrator) To(ctx context.Context, target int) (int, error) {
+	if target > 0 && !slices.ContainsFunc(m.migrations, func(mig Migration) bool { return mig.Version == target }) {
+		return 0, fmt.Errorf("unknown migration version %d", target)
+	}
+
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn, applied map[int]time.Time) error {
+		if len(applied) == 0 && m.Adopt != nil {
+			if err := m.Adopt(ctx, conn); err != nil {
+				return fmt.Errorf("error adopting existing schema: %w", err)
+			}
+		}
+
+		for _, mig := range m.migrations {
+			if _, done := applied[mig.Version]; done || (target >= 0 && mig.Version > target) {
+				continue
+			}
+			if err := m.run(ctx, conn, mig, mig.Up, true); err != nil {
+				return err
+			}
+			count++
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && target >= 0; i-- {
+			mig := m.migrations[i]
+			if _, done := applied[mig.Version]; !done || mig.Version <= target {
+				continue
+			}
+			if err := m.run(ctx, conn, mig, mig.Down, false); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down reverts the last steps applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	target := 0
+	seen := 0
+	for i := len(statuses) - 1; i >= 0; i-- {
+		if statuses[i].AppliedAt == nil {
+			continue
+		}
+		if seen == steps {
+			target = statuses[i].Version
+			break
+		}
+		seen++
+	}
+	return m.To(ctx, target)
+}
+
+// Status lists every known migration with its applied time
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sql.Conn, applied map[int]time.Time) error {
+		for _, mig := range m.migrations {
+			s := Status{Migration: mig}
+			if at, ok := applied[mig.Version]; ok {
+				s.AppliedAt = &at
+			}
+			statuses = append(statuses, s)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// run applies one direction of a migration and records it. Statements run
+// in a transaction, but MySQL commits DDL implicitly, so a failing MySQL
+// migration may be left half applied; keep them small and idempotent.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, mig Migration, body string, up bool) error {
+	direction := "up"
+	if !up {
+		direction = "down"
+		if strings.TrimSpace(body) == "" {
+			return fmt.Errorf("migration %04d_%s has no down file", mig.Version, mig.Name)
+		}
+	}
+	log.Printf("🔧 Migrating %s %04d_%s", direction, mig.Version, mig.Name)
+
+	tx, err := m.begin(ctx, conn, mig.Version)
+	if err != nil {
+		return fmt.Errorf("error starting migration %d: %w", mig.Version, err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range SplitStatements(body) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("migration %04d_%s %s failed: %w", mig.Version, mig.Name, direction, err)
+		}
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, m.bind(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`), mig.Version, mig.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, m.bind(`DELETE FROM schema_migrations WHERE version = ?`), mig.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("error recording migration %d: %w", mig.Version, err)
+	}
+	return tx.Commit()
+}
+
+// migrationTx is the transaction, or savepoint, one migration runs in
+type migrationTx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	Commit() error
+	Rollback() error
+}
+
+// begin starts a transaction for one migration, or a savepoint when the
+// lock already holds a transaction on conn
+func (m *Migrator) begin(ctx context.Context, conn *sql.Conn, version int) (migrationTx, error) {
+	if _, ok := m.locker.(txLocker); !ok {
+		return conn.BeginTx(ctx, nil)
+	}
+
+	sp := &savepoint{ctx: ctx, conn: conn, name: fmt.Sprintf("migration_%d", version)}
+	if _, err := conn.ExecContext(ctx, `SAVEPOINT `+sp.name); err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
+
+type savepoint struct {
+	ctx  context.Context
+	conn *sql.Conn
+	name string
+	done bool
+}
+
+func (sp *savepoint) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return sp.conn.ExecContext(ctx, query, args...)
+}
+
+func (sp *savepoint) Commit() error {
+	if _, err := sp.conn.ExecContext(sp.ctx, `RELEASE `+sp.name); err != nil {
+		return err
+	}
+	sp.done = true
+	return nil
+}
+
+// Rollback undoes the migration, leaving the ones before it to be committed
+func (sp *savepoint) Rollback() error {
+	if sp.done {
+		return sql.ErrTxDone
+	}
+	sp.done = true
+	ctx := context.WithoutCancel(sp.ctx)
+	if _, err := sp.conn.ExecContext(ctx, `ROLLBACK TO `+sp.name); err != nil {
+		return err
+	}
+	_, err := sp.conn.ExecContext(ctx, `RELEASE `+sp.name)
+	return err
+}
+
+// SplitStatements splits a migration body on the semicolons outside quotes
+// and comments, dropping comments and empty statements. Quotes inside
+// strings are escaped by doubling them, as in standard SQL.
+func SplitStatements(body string) []string {
+	var stmts []string
+	var current strings.Builder
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			end := closingQuote(body, i)
+			current.WriteString(body[i : end+1])
+			i = end
+		case strings.HasPrefix(body[i:], "--"):
+			// Skip to the end of the line, keeping the newline
+			end := strings.IndexByte(body[i:], '\n')
+			if end < 0 {
+				end = len(body) - i
+			}
+			i += end - 1
+		case strings.HasPrefix(body[i:], "/*"):
+			end := strings.Index(body[i+2:], "*/")
+			if end < 0 {
+				end = len(body) - i - 2
+			}
+			current.WriteByte(' ')
+			i += end + 3
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return stmts
+}
+
+// closingQuote returns the index of the quote closing the one at start, or
+// the end of body if it is never closed
+func closingQuote(body string, start int) int {
+	quote := body[start]
+	for i := start + 1; i < len(body); i++ {
+		if body[i] != quote {
+			continue
+		}
+		if i+1 < len(body) && body[i+1] == quote {
+			i++ // Doubled quote
+			continue
+		}
+		return i
+	}
+	return len(body) - 1
+}
+
+func (m *Migrator) bind(query string) string {
+	if m.Bind == nil {
+		return query
+	}
+	return m.Bind(query)
+}
@@ -0,0 +1,228 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// testMigrations create two tables, one per version
+var testMigrations = []Migration{
+	{Version: 1, Name: "accounts", Up: "CREATE TABLE accounts (id INTEGER PRIMARY KEY);", Down: "DROP TABLE accounts;"},
+	{Version: 2, Name: "entries", Up: "CREATE TABLE entries (id INTEGER PRIMARY KEY);", Down: "DROP TABLE entries;"},
+}
+
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "migrate.db")+"?_pragma=busy_timeout(5000)&_time_format=sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newSQLiteMigrator(t *testing.T, migrations []Migration) (*Migrator, *sql.DB) {
+	t.Helper()
+	db := openSQLite(t)
+	return New(db, migrations, SQLiteLock{Timeout: time.Second}), db
+}
+
+func TestToRejectsUnknownVersions(t *testing.T) {
+	m, _ := newSQLiteMigrator(t, testMigrations)
+	ctx := context.Background()
+
+	if _, err := m.To(ctx, 3); err == nil || !strings.Contains(err.Error(), "unknown migration version 3") {
+		t.Fatalf("To(3) = %v, want an unknown version error", err)
+	}
+	if applied, err := m.To(ctx, 2); err != nil || applied != 2 {
+		t.Fatalf("To(2) = %d, %v", applied, err)
+	}
+	if reverted, err := m.To(ctx, 0); err != nil || reverted != 2 {
+		t.Fatalf("To(0) = %d, %v, want both reverted", reverted, err)
+	}
+}
+
+func hasTable(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count > 0
+}
+
+func appliedVersionsOf(t *testing.T, m *Migrator) []int {
+	t.Helper()
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	var versions []int
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			versions = append(versions, status.Version)
+		}
+	}
+	return versions
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("orders by version number", func(t *testing.T) {
+		migrations, err := Load(fstest.MapFS{
+			"10_later.up.sql":   {Data: []byte("SELECT 10;")},
+			"2_early.up.sql":    {Data: []byte("SELECT 2;")},
+			"2_early.down.sql":  {Data: []byte("SELECT -2;")},
+			"1_first.up.sql":    {Data: []byte("SELECT 1;")},
+			"README.md":         {Data: []byte("not a migration")},
+			"3_Bad-Name.up.sql": {Data: []byte("SELECT 3;")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, mig := range migrations {
+			got = append(got, mig.Name)
+		}
+		if want := []string{"first", "early", "later"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		if migrations[1].Down != "SELECT -2;" || migrations[2].Down != "" {
+			t.Errorf("down files not matched to their versions: %+v", migrations)
+		}
+	})
+
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{"missing up file", fstest.MapFS{"1_first.down.sql": {Data: []byte("SELECT 1;")}}, "has no up file"},
+		{"empty up file", fstest.MapFS{"1_first.up.sql": {Data: []byte("  \n")}}, "has no up file"},
+		{"two names for a version", fstest.MapFS{
+			"1_first.up.sql":   {Data: []byte("SELECT 1;")},
+			"1_other.down.sql": {Data: []byte("SELECT 1;")},
+		}, "two names"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.fsys); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"one per line", "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n", []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"}},
+		{"several on a line", "DROP TABLE a; DROP TABLE b;", []string{"DROP TABLE a", "DROP TABLE b"}},
+		{"last without semicolon", "DROP TABLE a;\nDROP TABLE b", []string{"DROP TABLE a", "DROP TABLE b"}},
+		{"multi-line statement", "CREATE TABLE a (\n  id INT\n);", []string{"CREATE TABLE a (\n  id INT\n)"}},
+		{"line comments", "-- header; with a semicolon\nDROP TABLE a; -- trailing; comment\n-- footer", []string{"DROP TABLE a"}},
+		{"block comments", "/* drop; them */ DROP TABLE a; /* unclosed", []string{"DROP TABLE a"}},
+		{"semicolon in a string", "INSERT INTO a VALUES ('x;y');", []string{"INSERT INTO a VALUES ('x;y')"}},
+		{"semicolon ending a string line", "INSERT INTO a VALUES ('x;\ny');", []string{"INSERT INTO a VALUES ('x;\ny')"}},
+		{"comment markers in a string", "INSERT INTO a VALUES ('-- not /* a comment');", []string{"INSERT INTO a VALUES ('-- not /* a comment')"}},
+		{"doubled quotes", "INSERT INTO a VALUES ('it''s; fine');", []string{"INSERT INTO a VALUES ('it''s; fine')"}},
+		{"quoted identifiers", "CREATE TABLE \"a;b\" (`c;d` INT);", []string{"CREATE TABLE \"a;b\" (`c;d` INT)"}},
+		{"only comments", "-- nothing\n\n;;\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitStatements(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitStatements(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpDownStatus(t *testing.T) {
+	m, db := newSQLiteMigrator(t, testMigrations)
+	ctx := context.Background()
+
+	if versions := appliedVersionsOf(t, m); len(versions) != 0 {
+		t.Fatalf("fresh database has %v applied", versions)
+	}
+
+	if applied, err := m.Up(ctx); err != nil || applied != 2 {
+		t.Fatalf("Up = %d, %v, want 2 applied", applied, err)
+	}
+	if applied, err := m.Up(ctx); err != nil || applied != 0 {
+		t.Fatalf("second Up = %d, %v, want nothing to apply", applied, err)
+	}
+	if versions := appliedVersionsOf(t, m); !reflect.DeepEqual(versions, []int{1, 2}) {
+		t.Fatalf("applied %v, want [1 2]", versions)
+	}
+
+	if reverted, err := m.Down(ctx, 1); err != nil || reverted != 1 {
+		t.Fatalf("Down(1) = %d, %v", reverted, err)
+	}
+	if !hasTable(t, db, "accounts") || hasTable(t, db, "entries") {
+		t.Error("Down(1) did not revert only the last migration")
+	}
+	if versions := appliedVersionsOf(t, m); !reflect.DeepEqual(versions, []int{1}) {
+		t.Fatalf("applied %v after Down, want [1]", versions)
+	}
+
+	if changed, err := m.To(ctx, 2); err != nil || changed != 1 {
+		t.Fatalf("To(2) = %d, %v", changed, err)
+	}
+	if changed, err := m.To(ctx, 1); err != nil || changed != 1 {
+		t.Fatalf("To(1) = %d, %v", changed, err)
+	}
+	if reverted, err := m.Down(ctx, 5); err != nil || reverted != 1 {
+		t.Fatalf("Down(5) = %d, %v, want the one left reverted", reverted, err)
+	}
+	if hasTable(t, db, "accounts") {
+		t.Error("accounts left after reverting every migration")
+	}
+}
+
+func TestFailedMigrationIsRolledBackAlone(t *testing.T) {
+	broken := append(testMigrations[:1:1], Migration{
+		Version: 2,
+		Name:    "broken",
+		Up:      "CREATE TABLE partial (id INTEGER);\nINSERT INTO missing VALUES (1);",
+	})
+	m, db := newSQLiteMigrator(t, broken)
+
+	if _, err := m.Up(context.Background()); err == nil || !strings.Contains(err.Error(), "0002_broken up failed") {
+		t.Fatalf("Up = %v, want migration 2 to fail", err)
+	}
+	if !hasTable(t, db, "accounts") {
+		t.Error("the migration before the failing one was rolled back")
+	}
+	if hasTable(t, db, "partial") {
+		t.Error("the failing migration was left half applied")
+	}
+	if versions := appliedVersionsOf(t, m); !reflect.DeepEqual(versions, []int{1}) {
+		t.Errorf("applied %v, want [1]", versions)
+	}
+}
+
+func TestDownWithoutDownFile(t *testing.T) {
+	m, _ := newSQLiteMigrator(t, []Migration{{Version: 1, Name: "forever", Up: "CREATE TABLE forever (id INTEGER);"}})
+	ctx := context.Background()
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Down(ctx, 1); err == nil || !strings.Contains(err.Error(), "has no down file") {
+		t.Errorf("Down = %v, want a missing down file error", err)
+	}
+}
@@ -0,0 +1,105 @@
+package rates
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Cache memoizes the table of each base for ttl. A ttl of zero keeps entries
+// for the lifetime of the cache, which is useful for per-run caching.
+// Concurrent misses for the same base share a single upstream call.
+// Cached tables are shared between callers and must not be modified.
+type Cache struct {
+	provider RateProvider
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	calls   map[string]*fetchCall
+}
+
+// CacheStatus describes where a table returned by Lookup came from
+type CacheStatus struct {
+	Hit       bool
+	FetchedAt time.Time
+}
+
+type cacheEntry struct {
+	table     *Table
+	fetchedAt time.Time
+}
+
+type fetchCall struct {
+	done  chan struct{}
+	entry cacheEntry
+	err   error
+}
+
+func NewCache(provider RateProvider, ttl time.Duration) *Cache {
+	return &Cache{
+		provider: provider,
+		ttl:      ttl,
+		entries:  make(map[string]cacheEntry),
+		calls:    make(map[string]*fetchCall),
+	}
+}
+
+func (c *Cache) Name() string {
+	return c.provider.Name()
+}
+
+func (c *Cache) Latest(ctx context.Context, base string) (*Table, error) {
+	table, _, err := c.Lookup(ctx, base)
+	return table, err
+}
+
+// Lookup returns the table for base together with its cache status. Callers
+// that joined an in-flight fetch get a live (non-hit) status.
+func (c *Cache) Lookup(ctx context.Context, base string) (*Table, CacheStatus, error) {
+	c.mu.Lock()
+	if entry, ok := c.entries[base]; ok && c.fresh(entry) {
+		c.mu.Unlock()
+		return entry.table, CacheStatus{Hit: true, FetchedAt: entry.fetchedAt}, nil
+	}
+
+	call, inflight := c.calls[base]
+	if !inflight {
+		call = &fetchCall{done: make(chan struct{})}
+		c.calls[base] = call
+		// The fetch outlives the request that triggered it so a client
+		// hanging up does not fail everyone waiting on the same call
+		go c.fetch(context.WithoutCancel(ctx), base, call)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, CacheStatus{}, ctx.Err()
+	}
+
+	if call.err != nil {
+		return nil, CacheStatus{}, call.err
+	}
+	return call.entry.table, CacheStatus{FetchedAt: call.entry.fetchedAt}, nil
+}
+
+func (c *Cache) fetch(ctx context.Context, base string, call *fetchCall) {
+	table, err := c.provider.Latest(ctx, base)
+
+	c.mu.Lock()
+	if err == nil {
+		call.entry = cacheEntry{table: table, fetchedAt: time.Now()}
+		c.entries[base] = call.entry
+	}
+	call.err = err
+	delete(c.calls, base)
+	c.mu.Unlock()
+
+	close(call.done)
+}
+
+func (c *Cache) fresh(entry cacheEntry) bool {
+	return c.ttl <= 0 || time.Since(entry.fetchedAt) < c.ttl
+}
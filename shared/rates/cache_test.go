@@ -0,0 +1,97 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingProvider holds every call until release is closed
+type blockingProvider struct {
+	fakeProvider
+	release chan struct{}
+}
+
+func (p *blockingProvider) Latest(ctx context.Context, base string) (*Table, error) {
+	<-p.release
+	return p.fakeProvider.Latest(ctx, base)
+}
+
+func TestCacheSharesConcurrentFetches(t *testing.T) {
+	provider := &blockingProvider{
+		fakeProvider: fakeProvider{name: "slow", rates: map[string]float64{"USD": 1.1}},
+		release:      make(chan struct{}),
+	}
+	cache := NewCache(provider, time.Minute)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.Latest(context.Background(), "EUR"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+
+	// Let every caller miss the cache before the fetch completes
+	time.Sleep(50 * time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Errorf("%d upstream calls for concurrent misses, want 1", calls)
+	}
+}
+
+func TestCacheRefetchesAfterTTL(t *testing.T) {
+	provider := &fakeProvider{name: "fast", rates: map[string]float64{"USD": 1.1}}
+	cache := NewCache(provider, 30*time.Millisecond)
+	ctx := context.Background()
+
+	if _, status, err := cache.Lookup(ctx, "EUR"); err != nil || status.Hit {
+		t.Fatalf("first lookup: hit %v, %v; want a live fetch", status.Hit, err)
+	}
+	if _, status, err := cache.Lookup(ctx, "EUR"); err != nil || !status.Hit {
+		t.Fatalf("second lookup: hit %v, %v; want a cache hit", status.Hit, err)
+	}
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Fatalf("%d upstream calls within the TTL, want 1", calls)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if _, status, err := cache.Lookup(ctx, "EUR"); err != nil || status.Hit {
+		t.Fatalf("lookup after the TTL: hit %v, %v; want a live fetch", status.Hit, err)
+	}
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Errorf("%d upstream calls after the TTL, want 2", calls)
+	}
+}
+
+func TestCacheDoesNotKeepErrors(t *testing.T) {
+	errDown := errors.New("provider down")
+	provider := &fakeProvider{name: "flaky", rates: map[string]float64{"USD": 1.1}, err: errDown}
+	cache := NewCache(provider, time.Minute)
+	ctx := context.Background()
+
+	if _, err := cache.Latest(ctx, "EUR"); !errors.Is(err, errDown) {
+		t.Fatalf("got %v, want the provider error", err)
+	}
+
+	provider.err = nil
+	table, err := cache.Latest(ctx, "EUR")
+	if err != nil {
+		t.Fatalf("error was cached: %v", err)
+	}
+	if table.Rates["USD"] != 1.1 || provider.calls.Load() != 2 {
+		t.Errorf("got %v after %d calls, want a fresh table from a second call", table.Rates, provider.calls.Load())
+	}
+}
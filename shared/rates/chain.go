@@ -0,0 +1,43 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Chain tries each provider in order and returns the first table served
+type Chain struct {
+	providers []RateProvider
+}
+
+func NewChain(providers ...RateProvider) *Chain {
+	return &Chain{providers: providers}
+}
+
+func (c *Chain) Name() string {
+	names := make([]string, len(c.providers))
+	for i, p := range c.providers {
+		names[i] = p.Name()
+	}
+	return "chain(" + strings.Join(names, ",") + ")"
+}
+
+func (c *Chain) Latest(ctx context.Context, base string) (*Table, error) {
+	var errs []error
+	for _, p := range c.providers {
+		table, err := p.Latest(ctx, base)
+		if err == nil {
+			return table, nil
+		}
+		log.Printf("⚠️ Rates provider %s failed, trying next: %v", p.Name(), err)
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fmt.Errorf("all rates providers failed: %w", errors.Join(errs...))
+}
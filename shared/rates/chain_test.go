@@ -0,0 +1,47 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestChainFallsBackInOrder(t *testing.T) {
+	errDown := errors.New("provider down")
+	first := &fakeProvider{name: "first", err: errDown}
+	second := &fakeProvider{name: "second", rates: map[string]float64{"USD": 1.1}}
+	third := &fakeProvider{name: "third", rates: map[string]float64{"USD": 1.2}}
+
+	table, err := NewChain(first, second, third).Latest(context.Background(), "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Source != "second" {
+		t.Errorf("served by %s, want second", table.Source)
+	}
+	if first.calls.Load() != 1 || second.calls.Load() != 1 || third.calls.Load() != 0 {
+		t.Errorf("calls: first %d, second %d, third %d; want 1, 1, 0",
+			first.calls.Load(), second.calls.Load(), third.calls.Load())
+	}
+}
+
+func TestChainReportsEveryFailure(t *testing.T) {
+	errA, errB := errors.New("timeout"), errors.New("quota exceeded")
+	_, err := NewChain(&fakeProvider{name: "a", err: errA}, &fakeProvider{name: "b", err: errB}).Latest(context.Background(), "EUR")
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatalf("got %v, want both provider errors", err)
+	}
+}
+
+func TestChainStopsWhenTheCallerGivesUp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	second := &fakeProvider{name: "second", rates: map[string]float64{"USD": 1.1}}
+
+	if _, err := NewChain(&fakeProvider{name: "first", err: context.Canceled}, second).Latest(ctx, "EUR"); err == nil {
+		t.Fatal("chain succeeded after the context was canceled")
+	}
+	if second.calls.Load() != 0 {
+		t.Error("chain tried the next provider after the context was canceled")
+	}
+}
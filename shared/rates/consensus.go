@@ -0,0 +1,160 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Consensus queries every provider concurrently and, per currency, keeps the
+// median of the rates that lie within maxDeviation (relative) of the median.
+// Currencies quoted by fewer than minSources agreeing providers are dropped,
+// so two providers that disagree yield no rate at all.
+type Consensus struct {
+	providers    []RateProvider
+	maxDeviation float64
+	minSources   int
+}
+
+func NewConsensus(maxDeviation float64, minSources int, providers ...RateProvider) *Consensus {
+	if minSources < 1 {
+		minSources = 1
+	}
+	return &Consensus{
+		providers:    providers,
+		maxDeviation: maxDeviation,
+		minSources:   minSources,
+	}
+}
+
+func (c *Consensus) Name() string {
+	return "consensus"
+}
+
+func (c *Consensus) Latest(ctx context.Context, base string) (*Table, error) {
+	tables := make([]*Table, len(c.providers))
+	errs := make([]error, len(c.providers))
+
+	var wg sync.WaitGroup
+	for i, p := range c.providers {
+		wg.Add(1)
+		go func(i int, p RateProvider) {
+			defer wg.Done()
+			tables[i], errs[i] = p.Latest(ctx, base)
+			if errs[i] != nil {
+				log.Printf("⚠️ Rates provider %s failed during consensus: %v", p.Name(), errs[i])
+				errs[i] = fmt.Errorf("%s: %w", p.Name(), errs[i])
+			}
+		}(i, p)
+	}
+	wg.Wait()
+
+	var answered []*Table
+	for _, t := range tables {
+		if t != nil {
+			answered = append(answered, t)
+		}
+	}
+	if len(answered) < c.minSources {
+		return nil, fmt.Errorf("consensus needs %d providers, %d answered: %w", c.minSources, len(answered), errors.Join(errs...))
+	}
+
+	result := &Table{
+		Base:    base,
+		Rates:   make(map[string]float64),
+		Source:  c.Name(),
+		Sources: make(map[string][]string),
+	}
+	for _, t := range answered {
+		if t.Timestamp > result.Timestamp {
+			result.Timestamp = t.Timestamp
+			result.Date = t.Date
+		}
+	}
+
+	var dropped []string
+	for code := range unionCodes(answered) {
+		var samples []sample
+		for _, t := range answered {
+			if rate, ok := t.Rates[code]; ok && rate > 0 {
+				samples = append(samples, sample{rate: rate, source: t.Source})
+			}
+		}
+
+		kept := c.discardOutliers(samples)
+		if len(kept) < c.minSources {
+			dropped = append(dropped, code)
+			continue
+		}
+
+		rates := make([]float64, len(kept))
+		sources := make([]string, len(kept))
+		for i, s := range kept {
+			rates[i] = s.rate
+			sources[i] = s.source
+		}
+		sort.Strings(sources)
+
+		result.Rates[code] = median(rates)
+		result.Sources[code] = sources
+	}
+
+	if len(dropped) > 0 {
+		sort.Strings(dropped)
+		log.Printf("⚠️ No consensus against %s for %d currencies: %v", base, len(dropped), dropped)
+	}
+
+	if len(result.Rates) == 0 {
+		return nil, fmt.Errorf("providers did not agree on any %s rate", base)
+	}
+	return result, nil
+}
+
+type sample struct {
+	rate   float64
+	source string
+}
+
+func (c *Consensus) discardOutliers(samples []sample) []sample {
+	if c.maxDeviation <= 0 || len(samples) < 2 {
+		return samples
+	}
+
+	rates := make([]float64, len(samples))
+	for i, s := range samples {
+		rates[i] = s.rate
+	}
+	m := median(rates)
+
+	var kept []sample
+	for _, s := range samples {
+		if math.Abs(s.rate-m)/m <= c.maxDeviation {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+func unionCodes(tables []*Table) map[string]struct{} {
+	codes := make(map[string]struct{})
+	for _, t := range tables {
+		for code := range t.Rates {
+			codes[code] = struct{}{}
+		}
+	}
+	return codes
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
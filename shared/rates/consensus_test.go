@@ -0,0 +1,141 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConsensus(t *testing.T) {
+	errDown := errors.New("provider down")
+
+	tests := []struct {
+		name         string
+		maxDeviation float64
+		minSources   int
+		providers    []*fakeProvider
+		want         map[string]float64
+		wantSources  map[string][]string
+		wantErr      string
+	}{
+		{
+			name:       "median of an odd count",
+			minSources: 2,
+			providers: []*fakeProvider{
+				{name: "a", rates: map[string]float64{"USD": 1.12}},
+				{name: "b", rates: map[string]float64{"USD": 1.10}},
+				{name: "c", rates: map[string]float64{"USD": 1.11}},
+			},
+			want:        map[string]float64{"USD": 1.11},
+			wantSources: map[string][]string{"USD": {"a", "b", "c"}},
+		},
+		{
+			name:       "median of an even count",
+			minSources: 2,
+			providers: []*fakeProvider{
+				{name: "a", rates: map[string]float64{"USD": 1.10}},
+				{name: "b", rates: map[string]float64{"USD": 1.12}},
+			},
+			want:        map[string]float64{"USD": 1.11},
+			wantSources: map[string][]string{"USD": {"a", "b"}},
+		},
+		{
+			name:         "outlier removed",
+			maxDeviation: 0.02,
+			minSources:   2,
+			providers: []*fakeProvider{
+				{name: "a", rates: map[string]float64{"USD": 1.10}},
+				{name: "b", rates: map[string]float64{"USD": 1.50}},
+				{name: "c", rates: map[string]float64{"USD": 1.11}},
+			},
+			want:        map[string]float64{"USD": 1.105},
+			wantSources: map[string][]string{"USD": {"a", "c"}},
+		},
+		{
+			name:         "currency without enough agreeing sources dropped",
+			maxDeviation: 0.02,
+			minSources:   2,
+			providers: []*fakeProvider{
+				{name: "a", rates: map[string]float64{"USD": 1.10, "GBP": 0.85, "JPY": 160}},
+				{name: "b", rates: map[string]float64{"USD": 1.10, "GBP": 0.95}},
+			},
+			want:        map[string]float64{"USD": 1.10},
+			wantSources: map[string][]string{"USD": {"a", "b"}},
+		},
+		{
+			name:         "no currency agreed on",
+			maxDeviation: 0.02,
+			minSources:   2,
+			providers: []*fakeProvider{
+				{name: "a", rates: map[string]float64{"USD": 1.10}},
+				{name: "b", rates: map[string]float64{"USD": 1.30}},
+			},
+			wantErr: "did not agree on any EUR rate",
+		},
+		{
+			name:       "partial provider errors",
+			minSources: 2,
+			providers: []*fakeProvider{
+				{name: "a", rates: map[string]float64{"USD": 1.10}},
+				{name: "b", err: errDown},
+				{name: "c", rates: map[string]float64{"USD": 1.12}},
+			},
+			want:        map[string]float64{"USD": 1.11},
+			wantSources: map[string][]string{"USD": {"a", "c"}},
+		},
+		{
+			name:       "fewer providers answered than MinSources",
+			minSources: 2,
+			providers: []*fakeProvider{
+				{name: "a", rates: map[string]float64{"USD": 1.10}},
+				{name: "b", err: errDown},
+			},
+			wantErr: "consensus needs 2 providers, 1 answered",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers := make([]RateProvider, len(tt.providers))
+			for i, p := range tt.providers {
+				providers[i] = p
+			}
+
+			table, err := NewConsensus(tt.maxDeviation, tt.minSources, providers...).Latest(context.Background(), "EUR")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(table.Rates) != len(tt.want) {
+				t.Errorf("rates %v, want %v", table.Rates, tt.want)
+			}
+			for code, want := range tt.want {
+				if got := table.Rates[code]; math.Abs(got-want) > 1e-9 {
+					t.Errorf("%s = %v, want %v", code, got, want)
+				}
+			}
+			if !reflect.DeepEqual(table.Sources, tt.wantSources) {
+				t.Errorf("sources %v, want %v", table.Sources, tt.wantSources)
+			}
+		})
+	}
+}
+
+func TestConsensusReportsEveryProviderError(t *testing.T) {
+	errA, errB := errors.New("timeout"), errors.New("quota exceeded")
+	consensus := NewConsensus(0.02, 1, &fakeProvider{name: "a", err: errA}, &fakeProvider{name: "b", err: errB})
+
+	_, err := consensus.Latest(context.Background(), "EUR")
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatalf("got %v, want both provider errors", err)
+	}
+}
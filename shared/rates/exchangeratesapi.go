@@ -0,0 +1,182 @@
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const exchangeRatesAPIDefaultURL = "https://api.exchangeratesapi.io/v1"
+
+// ExchangeRatesResponse represents the response from exchangeratesapi.io
+type ExchangeRatesResponse struct {
+	Success   bool               `json:"success"`
+	Timestamp int64              `json:"timestamp"`
+	Base      string             `json:"base"`
+	Date      string             `json:"date"`
+	Rates     map[string]float64 `json:"rates"`
+}
+
+// ExchangeRatesAPI fetches rates from exchangeratesapi.io. The free plan
+// only serves EUR as base, so other bases are computed locally.
+type ExchangeRatesAPI struct {
+	apiKey    string
+	baseURL   string
+	fixedBase string
+	client    *http.Client
+}
+
+func NewExchangeRatesAPI(apiKey, baseURL string, client *http.Client) *ExchangeRatesAPI {
+	if baseURL == "" {
+		baseURL = exchangeRatesAPIDefaultURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &ExchangeRatesAPI{
+		apiKey:    apiKey,
+		baseURL:   baseURL,
+		fixedBase: "EUR",
+		client:    client,
+	}
+}
+
+func (p *ExchangeRatesAPI) Name() string {
+	return ProviderExchangeRatesAPI
+}
+
+func (p *ExchangeRatesAPI) Latest(ctx context.Context, base string) (*Table, error) {
+	query := url.Values{}
+	query.Set("access_key", p.apiKey)
+	query.Set("base", p.fixedBase)
+
+	var exchangeResp ExchangeRatesResponse
+	if err := p.get(ctx, "/latest", query, &exchangeResp); err != nil {
+		return nil, err
+	}
+
+	table := &Table{
+		Base:      exchangeResp.Base,
+		Date:      exchangeResp.Date,
+		Timestamp: exchangeResp.Timestamp,
+		Rates:     exchangeResp.Rates,
+		Source:    p.Name(),
+	}
+	if table.Base == base {
+		return table, nil
+	}
+	return table.Rebase(base)
+}
+
+// ExchangeRatesTimeSeriesResponse represents the time-series response from exchangeratesapi.io
+type ExchangeRatesTimeSeriesResponse struct {
+	Success   bool                          `json:"success"`
+	StartDate string                        `json:"start_date"`
+	EndDate   string                        `json:"end_date"`
+	Base      string                        `json:"base"`
+	Rates     map[string]map[string]float64 `json:"rates"`
+}
+
+// Historical uses the per-date endpoint for a single day, which the free
+// plan allows, and the time-series endpoint for longer ranges
+func (p *ExchangeRatesAPI) Historical(ctx context.Context, base string, symbols []string, start, end time.Time) ([]*Table, error) {
+	query := url.Values{}
+	query.Set("access_key", p.apiKey)
+	query.Set("base", p.fixedBase)
+	query.Set("symbols", strings.Join(append(append([]string{}, symbols...), base), ","))
+
+	startDate := start.UTC().Format("2006-01-02")
+	endDate := end.UTC().Format("2006-01-02")
+
+	daily := make(map[string]map[string]float64)
+	if startDate == endDate {
+		var exchangeResp ExchangeRatesResponse
+		if err := p.get(ctx, "/"+startDate, query, &exchangeResp); err != nil {
+			return nil, err
+		}
+		daily[exchangeResp.Date] = exchangeResp.Rates
+	} else {
+		query.Set("start_date", startDate)
+		query.Set("end_date", endDate)
+
+		var seriesResp ExchangeRatesTimeSeriesResponse
+		if err := p.get(ctx, "/timeseries", query, &seriesResp); err != nil {
+			return nil, err
+		}
+		daily = seriesResp.Rates
+	}
+
+	dates := make([]string, 0, len(daily))
+	for date := range daily {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	tables := make([]*Table, 0, len(dates))
+	for _, date := range dates {
+		timestamp, err := dayTimestamp(date)
+		if err != nil {
+			return nil, err
+		}
+
+		table, err := (&Table{
+			Base:      p.fixedBase,
+			Date:      date,
+			Timestamp: timestamp,
+			Rates:     daily[date],
+			Source:    p.Name(),
+		}).Rebase(base)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", date, err)
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+func (p *ExchangeRatesAPI) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call exchange rates API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return newRateLimitError(p.Name(), resp)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("exchange rates API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var envelope struct {
+		Success bool `json:"success"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	if !envelope.Success {
+		return fmt.Errorf("exchange rates API returned success=false: %s", string(body))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	return nil
+}
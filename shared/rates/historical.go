@@ -0,0 +1,48 @@
+package rates
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HistoricalProvider serves past daily tables
+type HistoricalProvider interface {
+	RateProvider
+	// Historical returns one table per available day in [start, end],
+	// quoted against base and limited to symbols, ordered by date
+	Historical(ctx context.Context, base string, symbols []string, start, end time.Time) ([]*Table, error)
+}
+
+// RateLimitError is returned when a provider rejects a call for exceeding
+// its quota. RetryAfter is zero when the provider did not say.
+type RateLimitError struct {
+	Provider   string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s rate limit reached, retry after %s", e.Provider, e.RetryAfter)
+	}
+	return fmt.Sprintf("%s rate limit reached", e.Provider)
+}
+
+func newRateLimitError(provider string, resp *http.Response) *RateLimitError {
+	err := &RateLimitError{Provider: provider}
+	if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && seconds > 0 {
+		err.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return err
+}
+
+// dayTimestamp is the observation time used for daily closing tables
+func dayTimestamp(date string) (int64, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return 0, fmt.Errorf("invalid date %q in provider response: %w", date, err)
+	}
+	return day.Unix(), nil
+}
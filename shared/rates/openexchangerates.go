@@ -0,0 +1,98 @@
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const openExchangeRatesDefaultURL = "https://openexchangerates.org/api"
+
+// OpenExchangeRatesResponse represents the response from openexchangerates.org
+type OpenExchangeRatesResponse struct {
+	Timestamp   int64              `json:"timestamp"`
+	Base        string             `json:"base"`
+	Rates       map[string]float64 `json:"rates"`
+	Error       bool               `json:"error"`
+	Status      int                `json:"status"`
+	Message     string             `json:"message"`
+	Description string             `json:"description"`
+}
+
+// OpenExchangeRates is the secondary vendor. Its free plan only serves USD
+// as base; the endpoint is configurable so compatible mirrors can be used.
+type OpenExchangeRates struct {
+	appID     string
+	baseURL   string
+	fixedBase string
+	client    *http.Client
+}
+
+func NewOpenExchangeRates(appID, baseURL string, client *http.Client) *OpenExchangeRates {
+	if baseURL == "" {
+		baseURL = openExchangeRatesDefaultURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &OpenExchangeRates{
+		appID:     appID,
+		baseURL:   baseURL,
+		fixedBase: "USD",
+		client:    client,
+	}
+}
+
+func (p *OpenExchangeRates) Name() string {
+	return ProviderOpenExchangeRates
+}
+
+func (p *OpenExchangeRates) Latest(ctx context.Context, base string) (*Table, error) {
+	query := url.Values{}
+	query.Set("app_id", p.appID)
+	query.Set("base", p.fixedBase)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/latest.json?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call open exchange rates API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, newRateLimitError(p.Name(), resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var oxrResp OpenExchangeRatesResponse
+	if err := json.Unmarshal(body, &oxrResp); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || oxrResp.Error {
+		return nil, fmt.Errorf("open exchange rates API returned status %d: %s", resp.StatusCode, oxrResp.Description)
+	}
+
+	table := &Table{
+		Base:      oxrResp.Base,
+		Date:      time.Unix(oxrResp.Timestamp, 0).UTC().Format("2006-01-02"),
+		Timestamp: oxrResp.Timestamp,
+		Rates:     oxrResp.Rates,
+		Source:    p.Name(),
+	}
+	if table.Base == base {
+		return table, nil
+	}
+	return table.Rebase(base)
+}
@@ -0,0 +1,186 @@
+// Package rates defines the exchange-rate provider contract shared by the
+// api and worker services, plus the vendor implementations behind it.
+package rates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Provider names accepted by New
+const (
+	ProviderExchangeRatesAPI  = "exchangeratesapi"
+	ProviderOpenExchangeRates = "openexchangerates"
+	ProviderStatic            = "static"
+)
+
+// Modes for combining several providers
+const (
+	ModeFallback  = "fallback"
+	ModeConsensus = "consensus"
+)
+
+var ErrUnsupportedCurrency = errors.New("currency not supported by the rates provider")
+
+// RateProvider returns the latest exchange-rate table quoted against base.
+// Implementations whose plan only serves a fixed base must rebase the table
+// themselves so callers always get the base they asked for.
+type RateProvider interface {
+	Name() string
+	Latest(ctx context.Context, base string) (*Table, error)
+}
+
+// Table is a set of rates quoted against a single base currency. Sources
+// lists, per currency, the providers a consensus rate was built from.
+type Table struct {
+	Base      string
+	Date      string
+	Timestamp int64
+	Rates     map[string]float64
+	Source    string
+	Sources   map[string][]string
+}
+
+// Rate returns the from->to rate, crossing through the table base when
+// neither currency is the base itself
+func (t *Table) Rate(from, to string) (float64, error) {
+	fromRate, err := t.quote(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := t.quote(to)
+	if err != nil {
+		return 0, err
+	}
+	return toRate / fromRate, nil
+}
+
+// Rebase returns a copy of the table quoted against base
+func (t *Table) Rebase(base string) (*Table, error) {
+	divisor, err := t.quote(base)
+	if err != nil {
+		return nil, err
+	}
+
+	rebased := &Table{
+		Base:      base,
+		Date:      t.Date,
+		Timestamp: t.Timestamp,
+		Rates:     make(map[string]float64, len(t.Rates)+1),
+		Source:    t.Source,
+		Sources:   t.Sources,
+	}
+	for code, rate := range t.Rates {
+		rebased.Rates[code] = rate / divisor
+	}
+	rebased.Rates[t.Base] = 1 / divisor
+	rebased.Rates[base] = 1
+
+	return rebased, nil
+}
+
+// Supports reports whether the table quotes code, i.e. whether the provider
+// lists it among its supported symbols
+func (t *Table) Supports(code string) bool {
+	_, err := t.quote(code)
+	return err == nil
+}
+
+// SourcesFor returns the providers behind the from->to rate
+func (t *Table) SourcesFor(from, to string) []string {
+	if t.Sources == nil {
+		return []string{t.Source}
+	}
+
+	seen := make(map[string]bool)
+	var sources []string
+	for _, code := range []string{from, to} {
+		for _, s := range t.Sources[code] {
+			if !seen[s] {
+				seen[s] = true
+				sources = append(sources, s)
+			}
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+func (t *Table) quote(code string) (float64, error) {
+	if code == t.Base {
+		return 1, nil
+	}
+	rate, exists := t.Rates[code]
+	if !exists || rate == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, code)
+	}
+	return rate, nil
+}
+
+// Options selects and configures the providers. With several providers,
+// Mode chooses between an ordered fallback chain and a consensus.
+type Options struct {
+	Providers              []string
+	Mode                   string
+	MaxDeviation           float64
+	MinSources             int
+	ExchangeRatesAPIKey    string
+	ExchangeRatesAPIURL    string
+	OpenExchangeRatesAppID string
+	OpenExchangeRatesURL   string
+	FixturePath            string
+	Timeout                time.Duration
+}
+
+// New builds the provider, chain or consensus described by opts
+func New(opts Options) (RateProvider, error) {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	names := opts.Providers
+	if len(names) == 0 {
+		names = []string{ProviderExchangeRatesAPI}
+	}
+
+	providers := make([]RateProvider, 0, len(names))
+	for _, name := range names {
+		p, err := newProvider(name, opts, client)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+
+	switch opts.Mode {
+	case "", ModeFallback:
+		return NewChain(providers...), nil
+	case ModeConsensus:
+		return NewConsensus(opts.MaxDeviation, opts.MinSources, providers...), nil
+	default:
+		return nil, fmt.Errorf("unknown rates mode %q", opts.Mode)
+	}
+}
+
+func newProvider(name string, opts Options, client *http.Client) (RateProvider, error) {
+	switch name {
+	case ProviderExchangeRatesAPI:
+		return NewExchangeRatesAPI(opts.ExchangeRatesAPIKey, opts.ExchangeRatesAPIURL, client), nil
+	case ProviderOpenExchangeRates:
+		return NewOpenExchangeRates(opts.OpenExchangeRatesAppID, opts.OpenExchangeRatesURL, client), nil
+	case ProviderStatic:
+		return LoadStaticFile(opts.FixturePath)
+	default:
+		return nil, fmt.Errorf("unknown rates provider %q", name)
+	}
+}
@@ -0,0 +1,26 @@
+package rates
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// fakeProvider serves a fixed table, or fails with err, and counts calls
+type fakeProvider struct {
+	name  string
+	rates map[string]float64
+	err   error
+	calls atomic.Int32
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) Latest(ctx context.Context, base string) (*Table, error) {
+	p.calls.Add(1)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &Table{Base: base, Rates: p.rates, Source: p.name, Timestamp: 1767225600}, nil
+}
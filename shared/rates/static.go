@@ -0,0 +1,79 @@
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// StaticProvider serves a fixed table, for tests and offline development
+type StaticProvider struct {
+	table *Table
+}
+
+func NewStaticProvider(table *Table) *StaticProvider {
+	return &StaticProvider{table: table}
+}
+
+// LoadStaticFile reads a fixture in the exchangeratesapi.io response format
+func LoadStaticFile(path string) (*StaticProvider, error) {
+	if path == "" {
+		return nil, fmt.Errorf("static rates provider requires a fixture path")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rates fixture: %w", err)
+	}
+
+	var fixture ExchangeRatesResponse
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("error parsing rates fixture: %w", err)
+	}
+	if fixture.Base == "" || len(fixture.Rates) == 0 {
+		return nil, fmt.Errorf("rates fixture %s must define base and rates", path)
+	}
+
+	return NewStaticProvider(&Table{
+		Base:      fixture.Base,
+		Date:      fixture.Date,
+		Timestamp: fixture.Timestamp,
+		Rates:     fixture.Rates,
+	}), nil
+}
+
+// Historical repeats the fixture for every day in the range
+func (p *StaticProvider) Historical(ctx context.Context, base string, symbols []string, start, end time.Time) ([]*Table, error) {
+	var tables []*Table
+	for day := start.UTC().Truncate(24 * time.Hour); !day.After(end); day = day.AddDate(0, 0, 1) {
+		table := *p.table
+		table.Source = p.Name()
+		table.Date = day.Format("2006-01-02")
+		table.Timestamp = day.Unix()
+
+		rebased, err := table.Rebase(base)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, rebased)
+	}
+	return tables, nil
+}
+
+func (p *StaticProvider) Name() string {
+	return ProviderStatic
+}
+
+func (p *StaticProvider) Latest(ctx context.Context, base string) (*Table, error) {
+	table := *p.table
+	table.Source = p.Name()
+	if table.Timestamp == 0 {
+		table.Timestamp = time.Now().Unix()
+	}
+	if table.Date == "" {
+		table.Date = time.Unix(table.Timestamp, 0).UTC().Format("2006-01-02")
+	}
+	return table.Rebase(base)
+}
@@ -0,0 +1,209 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// legacyUpgrade is one of the idempotent ALTERs the api ran on startup
+// before versioned migrations, kept so databases from that era can be
+// adopted
+type legacyUpgrade struct {
+	table       string
+	description string
+	needed      func(ctx context.Context, conn *sql.Conn) (bool, error)
+	stmt        string
+}
+
+var legacyUpgrades = []legacyUpgrade{
+	{
+		table:       "favorite_conversions",
+		description: "favorite_conversions.direction",
+		needed:      columnMissing("favorite_conversions", "direction"),
+		stmt:        `ALTER TABLE favorite_conversions ADD COLUMN direction VARCHAR(16) NOT NULL DEFAULT 'above' AFTER threshold`,
+	},
+	{
+		table:       "favorite_conversions",
+		description: "favorite_conversions.last_rate",
+		needed:      columnMissing("favorite_conversions", "last_rate"),
+		stmt:        `ALTER TABLE favorite_conversions ADD COLUMN last_rate DOUBLE NULL AFTER direction`,
+	},
+	{
+		table:       "favorite_conversions",
+		description: "favorite_conversions.rule_type",
+		needed:      columnMissing("favorite_conversions", "rule_type"),
+		stmt:        `ALTER TABLE favorite_conversions ADD COLUMN rule_type VARCHAR(16) NOT NULL DEFAULT 'threshold' AFTER last_rate`,
+	},
+	{
+		table:       "favorite_conversions",
+		description: "favorite_conversions.window_seconds",
+		needed:      columnMissing("favorite_conversions", "window_seconds"),
+		stmt:        `ALTER TABLE favorite_conversions ADD COLUMN window_seconds INT NULL AFTER rule_type`,
+	},
+	{
+		table:       "favorite_conversions",
+		description: "favorite_conversions.armed",
+		needed:      columnMissing("favorite_conversions", "armed"),
+		stmt:        `ALTER TABLE favorite_conversions ADD COLUMN armed BOOLEAN NOT NULL DEFAULT TRUE AFTER window_seconds`,
+	},
+	{
+		table:       "favorite_conversions",
+		description: "favorite_conversions.last_notified_at",
+		needed:      columnMissing("favorite_conversions", "last_notified_at"),
+		stmt:        `ALTER TABLE favorite_conversions ADD COLUMN last_notified_at TIMESTAMP NULL AFTER armed`,
+	},
+	{
+		table:       "favorite_conversions",
+		description: "favorite_conversions.last_notified_rate",
+		needed:      columnMissing("favorite_conversions", "last_notified_rate"),
+		stmt:        `ALTER TABLE favorite_conversions ADD COLUMN last_notified_rate DOUBLE NULL AFTER last_notified_at`,
+	},
+	{
+		table:       "favorite_conversions",
+		description: "favorite_conversions.channel",
+		needed:      columnMissing("favorite_conversions", "channel"),
+		stmt:        `ALTER TABLE favorite_conversions ADD COLUMN channel VARCHAR(16) NOT NULL DEFAULT 'email' AFTER last_notified_rate`,
+	},
+	{
+		table:       "favorite_conversions",
+		description: "favorite_conversions.target",
+		needed:      columnMissing("favorite_conversions", "target"),
+		stmt:        `ALTER TABLE favorite_conversions ADD COLUMN target VARCHAR(2048) NULL AFTER channel`,
+	},
+	{
+		table:       "favorite_conversions",
+		description: "favorite_conversions.webhook_url -> channel/target",
+		needed:      columnPresent("favorite_conversions", "webhook_url"),
+		stmt:        `UPDATE favorite_conversions SET channel = 'webhook', target = webhook_url WHERE webhook_url IS NOT NULL`,
+	},
+	{
+		table:       "favorite_conversions",
+		description: "drop favorite_conversions.webhook_url",
+		needed:      columnPresent("favorite_conversions", "webhook_url"),
+		stmt:        `ALTER TABLE favorite_conversions DROP COLUMN webhook_url`,
+	},
+	{
+		table:       "favorite_conversions",
+		description: "favorite_conversions.unique_watch_rule",
+		needed:      indexMissing("favorite_conversions", "unique_watch_rule"),
+		stmt:        `ALTER TABLE favorite_conversions ADD UNIQUE KEY unique_watch_rule (email, currency_origin, currency_destination, direction, rule_type)`,
+	},
+	{
+		table:       "notification_outbox",
+		description: "notification_outbox.channel",
+		needed:      columnMissing("notification_outbox", "channel"),
+		stmt:        `ALTER TABLE notification_outbox ADD COLUMN channel VARCHAR(16) NOT NULL DEFAULT 'email' AFTER payload`,
+	},
+	{
+		table:       "notification_outbox",
+		description: "notification_outbox.target",
+		needed:      columnMissing("notification_outbox", "target"),
+		stmt:        `ALTER TABLE notification_outbox ADD COLUMN target VARCHAR(2048) NULL AFTER channel`,
+	},
+	{
+		table:       "notification_outbox",
+		description: "notification_outbox.webhook_url -> channel/target",
+		needed:      columnPresent("notification_outbox", "webhook_url"),
+		stmt:        `UPDATE notification_outbox SET channel = 'webhook', target = webhook_url WHERE webhook_url IS NOT NULL`,
+	},
+	{
+		table:       "notification_outbox",
+		description: "drop notification_outbox.webhook_url",
+		needed:      columnPresent("notification_outbox", "webhook_url"),
+		stmt:        `ALTER TABLE notification_outbox DROP COLUMN webhook_url`,
+	},
+	{
+		table:       "notification_outbox",
+		description: "notification_outbox.delivered_channels",
+		needed:      columnMissing("notification_outbox", "delivered_channels"),
+		stmt:        `ALTER TABLE notification_outbox ADD COLUMN delivered_channels VARCHAR(255) NULL AFTER target`,
+	},
+	{
+		table:       "favorite_conversions",
+		description: "drop favorite_conversions.unique_email",
+		needed:      indexPresent("favorite_conversions", "unique_email"),
+		stmt:        `ALTER TABLE favorite_conversions DROP INDEX unique_email`,
+	},
+	{
+		table:       "favorite_conversions",
+		description: "drop favorite_conversions.unique_watch",
+		needed:      indexPresent("favorite_conversions", "unique_watch"),
+		stmt:        `ALTER TABLE favorite_conversions DROP INDEX unique_watch`,
+	},
+}
+
+// adoptLegacyMySQL brings a schema created before versioned migrations to
+// the baseline. Tables that don't exist yet are left to the baseline.
+func adoptLegacyMySQL(ctx context.Context, conn *sql.Conn) error {
+	for _, upgrade := range legacyUpgrades {
+		exists, err := tableExists(ctx, conn, upgrade.table)
+		if err != nil {
+			return fmt.Errorf("error checking %s: %w", upgrade.table, err)
+		}
+		if !exists {
+			continue
+		}
+
+		needed, err := upgrade.needed(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("error checking %s: %w", upgrade.description, err)
+		}
+		if !needed {
+			continue
+		}
+
+		log.Printf("🔧 Adopting legacy schema: %s", upgrade.description)
+		if _, err := conn.ExecContext(ctx, upgrade.stmt); err != nil {
+			return fmt.Errorf("error applying %s: %w", upgrade.description, err)
+		}
+	}
+	return nil
+}
+
+func tableExists(ctx context.Context, conn *sql.Conn, table string) (bool, error) {
+	var count int
+	err := conn.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`, table).Scan(&count)
+	return count > 0, err
+}
+
+func columnMissing(table, column string) func(ctx context.Context, conn *sql.Conn) (bool, error) {
+	return func(ctx context.Context, conn *sql.Conn) (bool, error) {
+		var count int
+		err := conn.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM information_schema.COLUMNS
+			WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`,
+			table, column).Scan(&count)
+		return count == 0, err
+	}
+}
+
+func columnPresent(table, column string) func(ctx context.Context, conn *sql.Conn) (bool, error) {
+	missing := columnMissing(table, column)
+	return func(ctx context.Context, conn *sql.Conn) (bool, error) {
+		notFound, err := missing(ctx, conn)
+		return !notFound, err
+	}
+}
+
+func indexPresent(table, index string) func(ctx context.Context, conn *sql.Conn) (bool, error) {
+	return func(ctx context.Context, conn *sql.Conn) (bool, error) {
+		var count int
+		err := conn.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM information_schema.STATISTICS
+			WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = ?`,
+			table, index).Scan(&count)
+		return count > 0, err
+	}
+}
+
+func indexMissing(table, index string) func(ctx context.Context, conn *sql.Conn) (bool, error) {
+	present := indexPresent(table, index)
+	return func(ctx context.Context, conn *sql.Conn) (bool, error) {
+		found, err := present(ctx, conn)
+		return !found, err
+	}
+}
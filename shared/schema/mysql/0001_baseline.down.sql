@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS notification_outbox;
+DROP TABLE IF EXISTS exchange_rates;
+DROP TABLE IF EXISTS favorite_conversions;
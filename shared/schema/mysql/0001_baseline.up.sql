@@ -0,0 +1,54 @@
+-- Baseline: every table, column and key the services had when versioned
+-- migrations were introduced, alert state, rules, channels, the rate history
+-- and the notification outbox included. On databases the api created before
+-- then, adoptLegacyMySQL (legacy.go) first adds what favorite_conversions
+-- lacks, and IF NOT EXISTS leaves the tables that exist as they are.
+CREATE TABLE IF NOT EXISTS favorite_conversions (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  email VARCHAR(255) NOT NULL,
+  currency_origin VARCHAR(10) NOT NULL,
+  currency_destination VARCHAR(10) NOT NULL,
+  threshold DOUBLE NOT NULL,
+  direction VARCHAR(16) NOT NULL DEFAULT 'above',
+  last_rate DOUBLE NULL,
+  rule_type VARCHAR(16) NOT NULL DEFAULT 'threshold',
+  window_seconds INT NULL,
+  armed BOOLEAN NOT NULL DEFAULT TRUE,
+  last_notified_at TIMESTAMP NULL,
+  last_notified_rate DOUBLE NULL,
+  channel VARCHAR(16) NOT NULL DEFAULT 'email',
+  target VARCHAR(2048) NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY unique_watch_rule (email, currency_origin, currency_destination, direction, rule_type)
+);
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  base VARCHAR(10) NOT NULL,
+  quote VARCHAR(10) NOT NULL,
+  rate DOUBLE NOT NULL,
+  provider VARCHAR(64) NOT NULL,
+  observed_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY unique_observation (base, quote, provider, observed_at),
+  KEY idx_quote_observed (quote, observed_at)
+);
+
+CREATE TABLE IF NOT EXISTS notification_outbox (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  favorite_id BIGINT NULL,
+  payload TEXT NOT NULL,
+  channel VARCHAR(16) NOT NULL DEFAULT 'email',
+  target VARCHAR(2048) NULL,
+  delivered_channels VARCHAR(255) NULL,
+  status VARCHAR(16) NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_error TEXT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  delivered_at TIMESTAMP NULL,
+  PRIMARY KEY (id),
+  KEY idx_status_next (status, next_attempt_at)
+);
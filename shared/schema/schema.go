@@ -0,0 +1,81 @@
+// Package schema holds the database migrations shared by the api and the
+// worker. Add a change as the next numbered pair of files in each of
+// mysql/, postgres/ and sqlite/; never edit a migration that has been
+// released.
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/dialect"
+	"github.com/joy-currency-conversion-GCP/shared/migrate"
+)
+
+//go:embed mysql/*.sql postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// lockName is the named lock both services take while migrating on MySQL
+// and PostgreSQL. SQLite uses its own write lock.
+const lockName = "joy_currency_schema_migrations"
+
+// Migrations returns the embedded migrations of driver in order. Each
+// driver has its own directory with the same versions.
+func Migrations(driver string) ([]migrate.Migration, error) {
+	switch driver {
+	case dialect.MySQL, dialect.Postgres, dialect.SQLite:
+	default:
+		return nil, fmt.Errorf("no migrations for database driver %q", driver)
+	}
+
+	dir, err := fs.Sub(files, driver)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.Load(dir)
+}
+
+// NewMigrator returns a migrator for the embedded migrations of driver. On
+// MySQL it adopts schemas created before migrations existed.
+func NewMigrator(db *sql.DB, driver string, lockTimeout time.Duration) (*migrate.Migrator, error) {
+	migrations, err := Migrations(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	switch driver {
+	case dialect.MySQL:
+		m := migrate.New(db, migrations, migrate.MySQLLock{Name: lockName, Timeout: lockTimeout})
+		m.Adopt = adoptLegacyMySQL
+		return m, nil
+	case dialect.Postgres:
+		d, _ := dialect.For(driver)
+		m := migrate.New(db, migrations, migrate.PostgresLock{Name: lockName, Timeout: lockTimeout})
+		m.Bind = d.Rebind
+		return m, nil
+	default:
+		return migrate.New(db, migrations, migrate.SQLiteLock{Timeout: lockTimeout}), nil
+	}
+}
+
+// Migrate applies every pending migration
+func Migrate(ctx context.Context, db *sql.DB, driver string, lockTimeout time.Duration) error {
+	m, err := NewMigrator(db, driver, lockTimeout)
+	if err != nil {
+		return err
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		return err
+	}
+	if applied > 0 {
+		log.Printf("✅ Applied %d schema migrations", applied)
+	}
+	return nil
+}
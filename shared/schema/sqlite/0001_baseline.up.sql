@@ -0,0 +1,50 @@
+-- Baseline: the SQLite equivalent of mysql/0001_baseline.up.sql. Keep the
+-- directories at the same versions.
+CREATE TABLE IF NOT EXISTS favorite_conversions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  email VARCHAR(255) NOT NULL,
+  currency_origin VARCHAR(10) NOT NULL,
+  currency_destination VARCHAR(10) NOT NULL,
+  threshold DOUBLE NOT NULL,
+  direction VARCHAR(16) NOT NULL DEFAULT 'above',
+  last_rate DOUBLE NULL,
+  rule_type VARCHAR(16) NOT NULL DEFAULT 'threshold',
+  window_seconds INT NULL,
+  armed BOOLEAN NOT NULL DEFAULT TRUE,
+  last_notified_at TIMESTAMP NULL,
+  last_notified_rate DOUBLE NULL,
+  channel VARCHAR(16) NOT NULL DEFAULT 'email',
+  target VARCHAR(2048) NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT unique_watch_rule UNIQUE (email, currency_origin, currency_destination, direction, rule_type)
+);
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  base VARCHAR(10) NOT NULL,
+  quote VARCHAR(10) NOT NULL,
+  rate DOUBLE NOT NULL,
+  provider VARCHAR(64) NOT NULL,
+  observed_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT unique_observation UNIQUE (base, quote, provider, observed_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_quote_observed ON exchange_rates (quote, observed_at);
+
+CREATE TABLE IF NOT EXISTS notification_outbox (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  favorite_id BIGINT NULL,
+  payload TEXT NOT NULL,
+  channel VARCHAR(16) NOT NULL DEFAULT 'email',
+  target VARCHAR(2048) NULL,
+  delivered_channels VARCHAR(255) NULL,
+  status VARCHAR(16) NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_error TEXT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  delivered_at TIMESTAMP NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_next ON notification_outbox (status, next_attempt_at);
@@ -0,0 +1,115 @@
+// Package webhook signs the threshold events the worker pushes to subscriber
+// URLs and lets receivers verify them.
+//
+// Each request carries the id of the event in IDHeader, the Unix time it
+// was sent in TimestampHeader and "sha256=" followed by the hex HMAC-SHA256
+// of "<id>.<timestamp>.<body>" in SignatureHeader. Receivers recompute the
+// HMAC with the shared secret and reject requests outside a small time
+// window, so a captured request can't be replayed later. Retries of an event
+// keep its id, so receivers that remember the ids they processed for that
+// window also drop duplicates and replays within it.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	IDHeader        = "X-Webhook-Id"
+
+	signaturePrefix = "sha256="
+
+	// DefaultTolerance is how old (or how far in the future) a request may be
+	DefaultTolerance = 5 * time.Minute
+
+	// maxBodySize bounds what VerifyRequest reads from a request
+	maxBodySize = 1 << 20
+)
+
+var (
+	ErrMissingSignature = errors.New("webhook: missing signature, timestamp or id")
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	ErrExpired          = errors.New("webhook: timestamp outside tolerance")
+)
+
+// Delivery is a verified webhook request
+type Delivery struct {
+	ID     string // Same on every retry of the event
+	SentAt time.Time
+	Body   []byte
+}
+
+// Sign returns the SignatureHeader value for event id with body sent at
+// timestamp
+func Sign(secret []byte, id string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id))
+	mac.Write([]byte("."))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest sets the id, timestamp and signature headers of req for body
+func SignRequest(req *http.Request, secret []byte, id string, body []byte, now time.Time) {
+	req.Header.Set(IDHeader, id)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(secret, id, now, body))
+}
+
+// Verify checks the signature, id and timestamp headers against body. A
+// tolerance of zero uses DefaultTolerance.
+func Verify(secret []byte, signature, id, timestamp string, body []byte, tolerance time.Duration, now time.Time) error {
+	if signature == "" || id == "" || timestamp == "" {
+		return ErrMissingSignature
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp %q", ErrInvalidSignature, timestamp)
+	}
+	sentAt := time.Unix(unix, 0)
+	if now.Sub(sentAt) > tolerance || sentAt.Sub(now) > tolerance {
+		return ErrExpired
+	}
+
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, id, sentAt, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// VerifyRequest reads and verifies an incoming webhook request. The
+// delivery is returned only when the signature is valid.
+func VerifyRequest(r *http.Request, secret []byte, tolerance time.Duration) (*Delivery, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("webhook: error reading body: %w", err)
+	}
+
+	id, timestamp := r.Header.Get(IDHeader), r.Header.Get(TimestampHeader)
+	if err := Verify(secret, r.Header.Get(SignatureHeader), id, timestamp, body, tolerance, time.Now()); err != nil {
+		return nil, err
+	}
+
+	unix, _ := strconv.ParseInt(timestamp, 10, 64)
+	return &Delivery{ID: id, SentAt: time.Unix(unix, 0), Body: body}, nil
+}
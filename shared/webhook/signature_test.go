@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testSecret = []byte("s3cret")
+	testBody   = []byte(`{"email":"ana@example.com","current_rate":1.1}`)
+	testNow    = time.Unix(1767225600, 0)
+)
+
+func TestVerify(t *testing.T) {
+	signature := Sign(testSecret, "42", testNow, testBody)
+	timestamp := strconv.FormatInt(testNow.Unix(), 10)
+
+	tests := []struct {
+		name      string
+		secret    []byte
+		signature string
+		id        string
+		timestamp string
+		body      []byte
+		now       time.Time
+		want      error
+	}{
+		{"valid", testSecret, signature, "42", timestamp, testBody, testNow, nil},
+		{"valid at the edge of the tolerance", testSecret, signature, "42", timestamp, testBody, testNow.Add(DefaultTolerance), nil},
+		{"wrong secret", []byte("other"), signature, "42", timestamp, testBody, testNow, ErrInvalidSignature},
+		{"tampered body", testSecret, signature, "42", timestamp, []byte(`{"email":"eve@example.com","current_rate":1.1}`), testNow, ErrInvalidSignature},
+		{"tampered id", testSecret, signature, "43", timestamp, testBody, testNow, ErrInvalidSignature},
+		{"tampered timestamp", testSecret, signature, "42", strconv.FormatInt(testNow.Unix()+1, 10), testBody, testNow, ErrInvalidSignature},
+		{"malformed timestamp", testSecret, signature, "42", "yesterday", testBody, testNow, ErrInvalidSignature},
+		{"expired", testSecret, signature, "42", timestamp, testBody, testNow.Add(DefaultTolerance + time.Second), ErrExpired},
+		{"from the future", testSecret, signature, "42", timestamp, testBody, testNow.Add(-DefaultTolerance - time.Second), ErrExpired},
+		{"without the sha256= prefix", testSecret, strings.TrimPrefix(signature, "sha256="), "42", timestamp, testBody, testNow, ErrInvalidSignature},
+		{"missing signature", testSecret, "", "42", timestamp, testBody, testNow, ErrMissingSignature},
+		{"missing id", testSecret, signature, "", timestamp, testBody, testNow, ErrMissingSignature},
+		{"missing timestamp", testSecret, signature, "42", "", testBody, testNow, ErrMissingSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.signature, tt.id, tt.timestamp, tt.body, 0, tt.now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	newRequest := func(body []byte) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader(body))
+		SignRequest(req, testSecret, "42", testBody, time.Now())
+		return req
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		delivery, err := VerifyRequest(newRequest(testBody), testSecret, DefaultTolerance)
+		if err != nil {
+			t.Fatalf("VerifyRequest: %v", err)
+		}
+		if delivery.ID != "42" || !bytes.Equal(delivery.Body, testBody) || time.Since(delivery.SentAt) > time.Minute {
+			t.Errorf("got delivery %+v", delivery)
+		}
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		delivery, err := VerifyRequest(newRequest([]byte(`{}`)), testSecret, DefaultTolerance)
+		if !errors.Is(err, ErrInvalidSignature) || delivery != nil {
+			t.Errorf("got %+v, %v, want ErrInvalidSignature and no delivery", delivery, err)
+		}
+	})
+
+	for _, header := range []string{SignatureHeader, TimestampHeader, IDHeader} {
+		t.Run("missing "+header, func(t *testing.T) {
+			req := newRequest(testBody)
+			req.Header.Del(header)
+			if _, err := VerifyRequest(req, testSecret, DefaultTolerance); !errors.Is(err, ErrMissingSignature) {
+				t.Errorf("got %v, want ErrMissingSignature", err)
+			}
+		})
+	}
+}
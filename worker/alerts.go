@@ -0,0 +1,28 @@
+package main
+
+import (
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+	"github.com/joy-currency-conversion-GCP/worker/config"
+)
+
+// AlertPolicy controls how often a favorite may notify. After notifying, a
+// favorite is disarmed until its value moves back past the threshold by
+// RearmBand (relative), and never notifies twice within Cooldown.
+type AlertPolicy struct {
+	Cooldown  time.Duration
+	RearmBand float64
+}
+
+func alertPolicyFromConfig(cfg *config.Config) AlertPolicy {
+	return AlertPolicy{
+		Cooldown:  cfg.AlertCooldown,
+		RearmBand: cfg.AlertRearmBand,
+	}
+}
+
+// inCooldown reports whether the favorite notified less than Cooldown ago
+func (p AlertPolicy) inCooldown(fav domain.FavoriteConversion, now time.Time) bool {
+	return fav.LastNotifiedAt.Valid && now.Sub(fav.LastNotifiedAt.Time) < p.Cooldown
+}
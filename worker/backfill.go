@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/rates"
+)
+
+// BackfillOptions describes one backfill run
+type BackfillOptions struct {
+	From       string
+	To         string
+	Start      time.Time
+	End        time.Time
+	ChunkDays  int
+	Delay      time.Duration
+	MaxRetries int
+}
+
+// runBackfill implements the "backfill" subcommand
+func (s *Server) runBackfill(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	from := fs.String("from", "EUR", "origin currency")
+	to := fs.String("to", "", "destination currency (required)")
+	start := fs.String("start", "", "first day to backfill, YYYY-MM-DD (required)")
+	end := fs.String("end", "", "last day to backfill, YYYY-MM-DD (default: yesterday)")
+	providerName := fs.String("provider", "", "provider to read from (default: first of RATE_PROVIDER)")
+	chunkDays := fs.Int("chunk", 1, "days per provider call; more than 1 uses the time-series endpoint (paid plans)")
+	delay := fs.Duration("delay", 2*time.Second, "pause between provider calls")
+	maxRetries := fs.Int("retries", 5, "attempts per call when the provider rate limits")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts := BackfillOptions{
+		From:       strings.ToUpper(*from),
+		To:         strings.ToUpper(*to),
+		ChunkDays:  *chunkDays,
+		Delay:      *delay,
+		MaxRetries: *maxRetries,
+	}
+	if opts.To == "" || *start == "" {
+		return fmt.Errorf("backfill requires -to and -start")
+	}
+
+	var err error
+	if opts.Start, err = time.Parse("2006-01-02", *start); err != nil {
+		return fmt.Errorf("invalid -start: %w", err)
+	}
+	opts.End = time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	if *end != "" {
+		if opts.End, err = time.Parse("2006-01-02", *end); err != nil {
+			return fmt.Errorf("invalid -end: %w", err)
+		}
+	}
+	if opts.End.Before(opts.Start) {
+		return fmt.Errorf("-end must not be before -start")
+	}
+	if opts.ChunkDays < 1 {
+		opts.ChunkDays = 1
+	}
+
+	ratesOpts := s.config.Rates
+	if *providerName != "" {
+		ratesOpts.Providers = []string{*providerName}
+	} else if len(ratesOpts.Providers) > 1 {
+		ratesOpts.Providers = ratesOpts.Providers[:1]
+	}
+	// An empty RATE_PROVIDER is left to rates.New, which defaults it
+	provider, err := rates.New(ratesOpts)
+	if err != nil {
+		return err
+	}
+	historical, ok := provider.(rates.HistoricalProvider)
+	if !ok {
+		return fmt.Errorf("rates provider %s does not serve historical rates", provider.Name())
+	}
+
+	return s.Backfill(ctx, historical, opts)
+}
+
+// Backfill stores the daily from->to rates between opts.Start and opts.End.
+// Days already stored for the provider are skipped, so an interrupted run
+// resumes where it stopped and re-running is harmless.
+func (s *Server) Backfill(ctx context.Context, provider rates.HistoricalProvider, opts BackfillOptions) error {
+	stored, err := s.backfilledDays(ctx, provider.Name(), opts)
+	if err != nil {
+		return err
+	}
+
+	var missing []time.Time
+	for day := opts.Start; !day.After(opts.End); day = day.AddDate(0, 0, 1) {
+		if !stored[day.Format("2006-01-02")] {
+			missing = append(missing, day)
+		}
+	}
+
+	total := int(opts.End.Sub(opts.Start).Hours()/24) + 1
+	log.Printf("📥 Backfilling %s->%s from %s: %d of %d days missing",
+		opts.From, opts.To, provider.Name(), len(missing), total)
+
+	done := total - len(missing)
+	for i, chunk := range chunkDays(missing, opts.ChunkDays) {
+		if i > 0 {
+			if err := sleepContext(ctx, opts.Delay); err != nil {
+				return err
+			}
+		}
+
+		chunkStart, chunkEnd := chunk[0], chunk[len(chunk)-1]
+		tables, err := fetchHistorical(ctx, provider, opts, chunkStart, chunkEnd)
+		if err != nil {
+			return fmt.Errorf("backfill stopped at %s (%d/%d days stored, re-run to resume): %w",
+				chunkStart.Format("2006-01-02"), done, total, err)
+		}
+
+		for _, table := range tables {
+			if _, err := s.RecordRates(ctx, pairTable(table, opts.From, opts.To)); err != nil {
+				return err
+			}
+		}
+
+		done += len(chunk)
+		log.Printf("📥 %s..%s stored (%d/%d days)",
+			chunkStart.Format("2006-01-02"), chunkEnd.Format("2006-01-02"), done, total)
+	}
+
+	log.Printf("✅ Backfill of %s->%s complete", opts.From, opts.To)
+	return nil
+}
+
+// fetchHistorical calls the provider, waiting out rate limits
+func fetchHistorical(ctx context.Context, provider rates.HistoricalProvider, opts BackfillOptions, start, end time.Time) ([]*rates.Table, error) {
+	wait := opts.Delay
+	for attempt := 1; ; attempt++ {
+		tables, err := provider.Historical(ctx, opts.From, []string{opts.To}, start, end)
+
+		var limitErr *rates.RateLimitError
+		if err == nil || !errors.As(err, &limitErr) || attempt >= opts.MaxRetries {
+			return tables, err
+		}
+
+		wait = max(wait*2, time.Second)
+		if limitErr.RetryAfter > 0 {
+			wait = limitErr.RetryAfter
+		}
+		log.Printf("⏳ %v, waiting %s (attempt %d/%d)", err, wait, attempt, opts.MaxRetries)
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backfilledDays returns the days that already have a daily closing rate
+// (observed at midnight UTC) for the pair and provider
+func (s *Server) backfilledDays(ctx context.Context, providerName string, opts BackfillOptions) (map[string]bool, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(`
+		SELECT DISTINCT observed_at FROM exchange_rates
+		WHERE base = ? AND quote = ? AND provider = ? AND observed_at >= ? AND observed_at <= ?
+	`), opts.From, opts.To, providerName, opts.Start, opts.End)
+	if err != nil {
+		return nil, fmt.Errorf("error querying backfilled days: %w", err)
+	}
+	defer rows.Close()
+
+	stored := make(map[string]bool)
+	for rows.Next() {
+		var observedAt time.Time
+		if err := rows.Scan(&observedAt); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		observedAt = observedAt.UTC()
+		if observedAt.Equal(observedAt.Truncate(24 * time.Hour)) {
+			stored[observedAt.Format("2006-01-02")] = true
+		}
+	}
+	return stored, rows.Err()
+}
+
+// chunkDays groups consecutive days into runs of at most size days
+func chunkDays(days []time.Time, size int) [][]time.Time {
+	var chunks [][]time.Time
+	for _, day := range days {
+		n := len(chunks)
+		if n > 0 {
+			last := chunks[n-1]
+			if len(last) < size && last[len(last)-1].AddDate(0, 0, 1).Equal(day) {
+				chunks[n-1] = append(last, day)
+				continue
+			}
+		}
+		chunks = append(chunks, []time.Time{day})
+	}
+	return chunks
+}
+
+// pairTable keeps only the currencies of the backfilled pair
+func pairTable(table *rates.Table, from, to string) *rates.Table {
+	pair := *table
+	pair.Rates = make(map[string]float64, 2)
+	for _, code := range []string{from, to} {
+		if rate, ok := table.Rates[code]; ok {
+			pair.Rates[code] = rate
+		}
+	}
+	return &pair
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/joy-currency-conversion-GCP/shared/dialect"
+	"github.com/joy-currency-conversion-GCP/shared/favorites"
+	"github.com/joy-currency-conversion-GCP/worker/config"
+)
+
+func TestRunBackfillWithoutProviders(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Rates.Providers = nil // RATE_PROVIDER=","
+	srv := NewServer(cfg, favorites.NewMemory(), nil, nil, nil, nil, dialect.Dialect{})
+
+	// There is no database to store the rates in, so this still fails, but
+	// only after picking the default provider
+	err := srv.runBackfill(context.Background(), []string{"-to", "USD", "-start", "2026-01-01", "-end", "2026-01-01"})
+	if err == nil || !strings.Contains(err.Error(), "database not initialized") {
+		t.Fatalf("got %v, want the missing database reported", err)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+)
+
+// channelRouter sends each notification through the notifier of its channel
+type channelRouter map[string]Notifier
+
+func (r channelRouter) SendNotification(ctx context.Context, notification EmailNotification) error {
+	channel := notification.Channel
+	if channel == "" {
+		channel = domain.ChannelEmail
+	}
+
+	n, ok := r[channel]
+	if !ok {
+		if channel == domain.ChannelWebhook {
+			return fmt.Errorf("favorite of %s uses a webhook but webhooks are disabled (WEBHOOKS_ENABLED)", notification.Email)
+		}
+		return fmt.Errorf("unknown notification channel %q", channel)
+	}
+	return n.SendNotification(ctx, notification)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+)
+
+// postJSON sends payload to a chat incoming-webhook URL
+func postJSON(ctx context.Context, client *http.Client, url string, payload any) error {
+	if url == "" {
+		return fmt.Errorf("no webhook url")
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error marshaling message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// validateChatTarget checks target before a message is built for it.
+// Targets stored before the host allowlist may point anywhere.
+func validateChatTarget(channel, target string) error {
+	return domain.ValidateChannel(channel, target)
+}
+
+// alertFacts are the label/value lines shown in chat messages
+func alertFacts(m alertMessage) [][2]string {
+	facts := [][2]string{
+		{"Pair", m.CurrencyOrigin + " → " + m.CurrencyDestination},
+		{"Current rate", fmt.Sprintf("%.4f", m.CurrentRate)},
+		{"Threshold", fmt.Sprintf("%v (%s %s)", m.Threshold, m.Rule, m.Direction)},
+	}
+	if m.Trend != "" {
+		facts = append(facts, [2]string{"Trend", m.Trend + " since last check"})
+	}
+	return facts
+}
+
+// SlackNotifier posts alerts to the Slack incoming webhook in the favorite's
+// target
+type SlackNotifier struct {
+	client *http.Client
+}
+
+func NewSlackNotifier(timeout time.Duration) *SlackNotifier {
+	return &SlackNotifier{client: &http.Client{Timeout: timeout}}
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type   string      `json:"type"`
+	Text   *slackText  `json:"text,omitempty"`
+	Fields []slackText `json:"fields,omitempty"`
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"` // Fallback for notifications
+	Blocks []slackBlock `json:"blocks"`
+}
+
+func (n *SlackNotifier) SendNotification(ctx context.Context, notification EmailNotification) error {
+	if err := validateChatTarget(domain.ChannelSlack, notification.Target); err != nil {
+		return err
+	}
+	m := newAlertMessage(notification)
+	title := fmt.Sprintf("Currency Alert: %s to %s", m.CurrencyOrigin, m.CurrencyDestination)
+
+	var fields []slackText
+	for _, fact := range alertFacts(m) {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*%s*\n%s", fact[0], fact[1])})
+	}
+
+	msg := slackMessage{
+		Text: title + ". " + m.Summary,
+		Blocks: []slackBlock{
+			{Type: "header", Text: &slackText{Type: "plain_text", Text: title}},
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: m.Summary}},
+			{Type: "section", Fields: fields},
+		},
+	}
+
+	if err := postJSON(ctx, n.client, notification.Target, msg); err != nil {
+		return fmt.Errorf("error posting to slack: %w", err)
+	}
+	return nil
+}
+
+// TeamsNotifier posts alerts as an Adaptive Card to the Teams incoming
+// webhook (connector or workflow) in the favorite's target
+type TeamsNotifier struct {
+	client *http.Client
+}
+
+func NewTeamsNotifier(timeout time.Duration) *TeamsNotifier {
+	return &TeamsNotifier{client: &http.Client{Timeout: timeout}}
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string           `json:"$schema"`
+	Type    string           `json:"type"`
+	Version string           `json:"version"`
+	Body    []map[string]any `json:"body"`
+}
+
+func (n *TeamsNotifier) SendNotification(ctx context.Context, notification EmailNotification) error {
+	if err := validateChatTarget(domain.ChannelTeams, notification.Target); err != nil {
+		return err
+	}
+	m := newAlertMessage(notification)
+
+	var facts []map[string]string
+	for _, fact := range alertFacts(m) {
+		facts = append(facts, map[string]string{"title": fact[0], "value": fact[1]})
+	}
+
+	msg := teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: teamsCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body: []map[string]any{
+					{"type": "TextBlock", "size": "Medium", "weight": "Bolder",
+						"text": fmt.Sprintf("Currency Alert: %s to %s", m.CurrencyOrigin, m.CurrencyDestination)},
+					{"type": "TextBlock", "wrap": true, "text": m.Summary},
+					{"type": "FactSet", "facts": facts},
+				},
+			},
+		}},
+	}
+
+	if err := postJSON(ctx, n.client, notification.Target, msg); err != nil {
+		return fmt.Errorf("error posting to teams: %w", err)
+	}
+	return nil
+}
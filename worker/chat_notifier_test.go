@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// recordingTransport answers every request with 200 and remembers the URLs
+type recordingTransport struct {
+	urls []string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.urls = append(rt.urls, req.URL.String())
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok")), Request: req}, nil
+}
+
+func TestChatNotifiersCheckTheTargetFirst(t *testing.T) {
+	slack := NewSlackNotifier(0)
+	teams := NewTeamsNotifier(0)
+
+	tests := []struct {
+		name     string
+		notifier Notifier
+		client   *http.Client
+		target   string
+		valid    bool
+	}{
+		{"slack webhook", slack, slack.client, "https://hooks.slack.com/services/T000/B000/XXXX", true},
+		{"slack wrong host", slack, slack.client, "https://example.com/services/T000/B000/XXXX", false},
+		{"slack over http", slack, slack.client, "http://hooks.slack.com/services/T000/B000/XXXX", false},
+		{"slack userinfo trick", slack, slack.client, "https://hooks.slack.com@169.254.169.254/services/T000", false},
+		{"teams webhook", teams, teams.client, "https://contoso.webhook.office.com/webhookb2/abc", true},
+		{"teams wrong host", teams, teams.client, "https://hooks.slack.com/services/T000/B000/XXXX", false},
+		{"teams over http", teams, teams.client, "http://contoso.webhook.office.com/webhookb2/abc", false},
+		{"teams userinfo trick", teams, teams.client, "https://contoso.webhook.office.com@evil.example/webhookb2/abc", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &recordingTransport{}
+			tt.client.Transport = transport
+
+			err := tt.notifier.SendNotification(context.Background(), EmailNotification{
+				Email:               "ana@example.com",
+				CurrencyOrigin:      "EUR",
+				CurrencyDestination: "USD",
+				CurrentRate:         1.1,
+				Threshold:           1,
+				Target:              tt.target,
+			})
+			if (err == nil) != tt.valid {
+				t.Fatalf("SendNotification = %v, want valid %v", err, tt.valid)
+			}
+			if tt.valid && (len(transport.urls) != 1 || transport.urls[0] != tt.target) {
+				t.Errorf("posted to %v, want %s", transport.urls, tt.target)
+			}
+			if !tt.valid && len(transport.urls) != 0 {
+				t.Errorf("posted to %v despite the invalid target", transport.urls)
+			}
+		})
+	}
+}
@@ -1,139 +1,169 @@
 package config
 
 import (
-	"context"
-	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
-	secretmanager "cloud.google.com/go/secretmanager/apiv1"
-	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
-	"github.com/joho/godotenv"
+	sharedconfig "github.com/joy-currency-conversion-GCP/shared/config"
 )
 
-type Environment string
+// Canales de envío de notificaciones
+const (
+	NotifierPubSub = "pubsub"
+	NotifierHTTP   = "http"
+	NotifierSMTP   = "smtp"
+)
 
+// Modos de cifrado SMTP
 const (
-	EnvLocal      Environment = "local"
-	EnvProduction Environment = "production"
+	SMTPStartTLS = "starttls"
+	SMTPImplicit = "tls"
+	SMTPNone     = "none"
 )
 
 type Config struct {
-	Port            string
-	Environment     Environment
-	APIKey          string
-	FunctionURL     string // URL de la Cloud Function para enviar emails
-	PubSubTopicID   string // Topic ID de Pub/Sub para notificaciones
-	DBConfig        DatabaseConfig
+	sharedconfig.Common
+	RatesCacheTTL          time.Duration // Reutiliza las tasas entre ejecuciones; 0 = solo durante una ejecución
+	AlertCooldown          time.Duration // Tiempo mínimo entre dos notificaciones del mismo favorito
+	AlertRearmBand         float64       // Margen relativo para volver a armar un favorito ya notificado
+	OutboxDispatchInterval time.Duration // Frecuencia del despachador de notificaciones; 0 lo desactiva
+	OutboxMaxAttempts      int           // Intentos antes de marcar una notificación como fallida
+	OutboxRetryBaseDelay   time.Duration // Espera tras el primer fallo; se duplica en cada intento
+	OutboxRetryMaxDelay    time.Duration // Espera máxima entre reintentos
+	OutboxLease            time.Duration // Tiempo que un despachador reserva un lote de notificaciones para enviarlo
+	NotifyTimeout          time.Duration // Tiempo máximo de una llamada HTTP de notificación
+	NotifyMaxAttempts      int           // Intentos inmediatos por notificación, incluido el primero
+	NotifyRetryBaseDelay   time.Duration
+	NotifyRetryMaxDelay    time.Duration
+	NotifyRetryJitter      float64 // Fracción aleatoria de cada espera (0 a 1)
+	Notifier               string  // pubsub, http o smtp
+	FunctionURL            string  // URL de la Cloud Function para enviar emails
+	PubSubTopicID          string  // Topic ID de Pub/Sub para notificaciones
+	SMTP                   SMTPConfig
+	WebhooksEnabled        bool           // Entrega a los favoritos del canal webhook
+	WebhookSecret          string         // Clave HMAC con la que se firman los webhooks
+	FanOut                 []FanOutTarget // Canales que reciben una copia de cada alerta
 }
 
-type DatabaseConfig struct {
-	Host           string
-	Port           string
-	Name           string
-	User           string
-	Password       string
-	ConnectionName string // Para Cloud SQL
+// FanOutTarget es un canal extra ("slack", "teams", "webhook" o "email") y
+// su destino (URL o dirección de correo)
+type FanOutTarget struct {
+	Channel string
+	Target  string
+}
+
+type SMTPConfig struct {
+	Host         string
+	Port         int
+	TLS          string // starttls, tls (implícito) o none
+	Username     string // Vacío = sin autenticación
+	Password     string
+	From         string
+	TemplatePath string // Plantilla opcional con los bloques "subject" y "body"
 }
 
 func Load() *Config {
-	env := detectEnvironment()
+	env := sharedconfig.DetectEnvironment()
 
 	log.Printf("🚀 Worker iniciando en modo: %s", env)
 
 	cfg := &Config{
-		Port:        getEnv("PORT", "8080"),
-		Environment: env,
-		DBConfig:    loadDatabaseConfig(env),
-		APIKey:      loadSecret("EXCHANGE_RATES_API_KEY", "EXCHANGE_RATES_API_KEY", env),
-		FunctionURL: loadSecret("FUNCTION_URL", "FUNCTION_URL", env),
-		PubSubTopicID: loadSecret("PUBSUB_TOPIC_ID", "PUBSUB_TOPIC_ID", env),
+		Common:                 sharedconfig.LoadCommon(),
+		RatesCacheTTL:          sharedconfig.GetEnvDuration("RATES_CACHE_TTL", 0),
+		AlertCooldown:          sharedconfig.GetEnvDuration("ALERT_COOLDOWN", time.Hour),
+		AlertRearmBand:         sharedconfig.GetEnvFloat("ALERT_REARM_BAND", 0.005),
+		OutboxDispatchInterval: sharedconfig.GetEnvDuration("OUTBOX_DISPATCH_INTERVAL", 30*time.Second),
+		OutboxMaxAttempts:      sharedconfig.GetEnvInt("OUTBOX_MAX_ATTEMPTS", 10),
+		OutboxRetryBaseDelay:   sharedconfig.GetEnvDuration("OUTBOX_RETRY_BASE_DELAY", 30*time.Second),
+		OutboxRetryMaxDelay:    sharedconfig.GetEnvDuration("OUTBOX_RETRY_MAX_DELAY", time.Hour),
+		OutboxLease:            sharedconfig.GetEnvDuration("OUTBOX_LEASE", 5*time.Minute),
+		NotifyTimeout:          sharedconfig.GetEnvDuration("NOTIFY_TIMEOUT", 10*time.Second),
+		NotifyMaxAttempts:      sharedconfig.GetEnvInt("NOTIFY_MAX_ATTEMPTS", 3),
+		NotifyRetryBaseDelay:   sharedconfig.GetEnvDuration("NOTIFY_RETRY_BASE_DELAY", 500*time.Millisecond),
+		NotifyRetryMaxDelay:    sharedconfig.GetEnvDuration("NOTIFY_RETRY_MAX_DELAY", 10*time.Second),
+		NotifyRetryJitter:      sharedconfig.GetEnvFloat("NOTIFY_RETRY_JITTER", 0.5),
+		Notifier:               strings.ToLower(sharedconfig.GetEnv("NOTIFIER", NotifierPubSub)),
 	}
 
-	return cfg
-}
-
-func detectEnvironment() Environment {
-	if os.Getenv("GCP_PROJECT_ID") != "" {
-		return EnvProduction
-	}
-	if os.Getenv("ENVIRONMENT") == "production" {
-		return EnvProduction
+	if sharedconfig.GetEnvBool("WEBHOOKS_ENABLED", false) {
+		cfg.WebhooksEnabled = true
+		cfg.WebhookSecret = sharedconfig.LoadSecret("WEBHOOK_SECRET", "WEBHOOK_SECRET", env)
 	}
-	return EnvLocal
-}
 
-func loadDatabaseConfig(env Environment) DatabaseConfig {
-	if env == EnvProduction {
-		log.Println("📦 Configurando Cloud SQL (producción)")
-		return DatabaseConfig{
-			ConnectionName: getEnv("CLOUD_SQL_CONNECTION_NAME", ""),
-			Name:           getEnv("DB_NAME", "currency_conversion"),
-			User:           loadSecret("DB_USER", "DB_USER", env),
-			Password:       loadSecret("DB_PASSWORD", "DB_PASSWORD", env),
-		}
+	if sharedconfig.GetEnvBool("NOTIFY_FANOUT_ENABLED", false) {
+		cfg.FanOut = parseFanOut(sharedconfig.LoadSecret("NOTIFY_FANOUT", "NOTIFY_FANOUT", env))
 	}
 
-	log.Println("💻 Configurando MySQL local (desarrollo)")
-	return DatabaseConfig{
-		Host:     getEnv("DB_HOST", "mysql"),
-		Port:     getEnv("DB_PORT", "3306"),
-		Name:     getEnv("DB_NAME", "currency_conversion"),
-		User:     getEnv("DB_USER", "app"),
-		Password: getEnv("DB_PASSWORD", "app_password"),
+	// Solo se cargan los secretos del canal elegido
+	switch cfg.Notifier {
+	case NotifierPubSub:
+		cfg.PubSubTopicID = sharedconfig.LoadSecret("PUBSUB_TOPIC_ID", "PUBSUB_TOPIC_ID", env)
+	case NotifierHTTP:
+		cfg.FunctionURL = sharedconfig.LoadSecret("FUNCTION_URL", "FUNCTION_URL", env)
+	case NotifierSMTP:
+		cfg.SMTP = loadSMTPConfig(env)
+	default:
+		log.Fatalf("❌ NOTIFIER desconocido: %s (usa pubsub, http o smtp)", cfg.Notifier)
 	}
+
+	return cfg
 }
 
-func loadSecret(envKey, secretName string, env Environment) string {
-	if env == EnvProduction {
-		log.Printf("🔐 Obteniendo '%s' desde Secret Manager", secretName)
-		return getSecretFromGCP(secretName)
-	}
+// LoadForMigrate carga solo lo que necesita el subcomando migrate, sin los
+// secretos de las notificaciones ni de los proveedores de tasas
+func LoadForMigrate() *Config {
+	return &Config{Common: sharedconfig.LoadDatabaseCommon()}
+}
 
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("Warning: .env file not found, usando variables de entorno del sistema")
-	}
-	value := os.Getenv(envKey)
-	if value == "" {
-		log.Fatalf("❌ Variable %s no encontrada en ambiente local", envKey)
+func loadSMTPConfig(env sharedconfig.Environment) SMTPConfig {
+	port := sharedconfig.GetEnvInt("SMTP_PORT", 587)
+
+	defaultTLS := SMTPStartTLS
+	if port == 465 {
+		defaultTLS = SMTPImplicit
 	}
-	return value
-}
 
-func getSecretFromGCP(secretName string) string {
-	projectID := os.Getenv("GCP_PROJECT_ID")
-	if projectID == "" {
-		log.Fatal("❌ GCP_PROJECT_ID no configurado")
+	smtpCfg := SMTPConfig{
+		Host:         sharedconfig.GetEnv("SMTP_SERVER", "smtp.gmail.com"),
+		Port:         port,
+		TLS:          strings.ToLower(sharedconfig.GetEnv("SMTP_TLS", defaultTLS)),
+		TemplatePath: os.Getenv("SMTP_TEMPLATE"),
 	}
 
-	ctx := context.Background()
-	client, err := secretmanager.NewClient(ctx)
-	if err != nil {
-		log.Fatalf("❌ Error creando cliente Secret Manager: %v", err)
+	switch smtpCfg.TLS {
+	case SMTPStartTLS, SMTPImplicit, SMTPNone:
+	default:
+		log.Fatalf("❌ SMTP_TLS desconocido: %s (usa starttls, tls o none)", smtpCfg.TLS)
 	}
-	defer client.Close()
 
-	name := fmt.Sprintf("projects/%s/secrets/%s/versions/latest", projectID, secretName)
-	req := &secretmanagerpb.AccessSecretVersionRequest{Name: name}
+	// Un relay local (p. ej. MailHog) no necesita credenciales
+	if sharedconfig.GetEnvBool("SMTP_AUTH", true) {
+		smtpCfg.Username = sharedconfig.LoadSecret("SMTP_USER", "SMTP_USER", env)
+		smtpCfg.Password = sharedconfig.LoadSecret("SMTP_PASSWORD", "SMTP_PASSWORD", env)
+	}
 
-	result, err := client.AccessSecretVersion(ctx, req)
-	if err != nil {
-		log.Fatalf("❌ Error accediendo al secreto %s: %v", secretName, err)
+	smtpCfg.From = sharedconfig.GetEnv("SMTP_FROM", smtpCfg.Username)
+	if smtpCfg.From == "" {
+		log.Fatal("❌ SMTP_FROM no configurado")
 	}
 
-	log.Printf("✅ Secreto '%s' obtenido correctamente", secretName)
-	return string(result.Payload.Data)
+	return smtpCfg
 }
 
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// parseFanOut lee una lista "canal=destino,canal=destino"
+func parseFanOut(value string) []FanOutTarget {
+	var targets []FanOutTarget
+	for _, item := range sharedconfig.SplitList(value) {
+		channel, target, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(target) == "" {
+			log.Fatalf("❌ NOTIFY_FANOUT inválido: %q (usa canal=destino)", item)
+		}
+		targets = append(targets, FanOutTarget{
+			Channel: strings.ToLower(strings.TrimSpace(channel)),
+			Target:  strings.TrimSpace(target),
+		})
 	}
-	return value
+	return targets
 }
-
-func (c *Config) IsProduction() bool {
-	return c.Environment == EnvProduction
-}
\ No newline at end of file
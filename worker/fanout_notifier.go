@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+)
+
+// FanOutChannel is one named destination of a FanOutNotifier
+type FanOutChannel struct {
+	Name     string
+	Notifier Notifier
+}
+
+// ChannelResult is the outcome of one channel of a fan-out
+type ChannelResult struct {
+	Channel string
+	Err     error
+	Elapsed time.Duration
+}
+
+// FanOutError reports the channels of a fan-out that failed. Results also
+// holds the ones that succeeded, so a retry can skip them.
+type FanOutError struct {
+	Results []ChannelResult
+}
+
+func (e *FanOutError) Error() string {
+	var failed []string
+	for _, r := range e.Results {
+		if r.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", r.Channel, r.Err))
+		}
+	}
+	return fmt.Sprintf("%d/%d channels failed: %s", len(failed), len(e.Results), strings.Join(failed, "; "))
+}
+
+func (e *FanOutError) Unwrap() []error {
+	var errs []error
+	for _, r := range e.Results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	return errs
+}
+
+// Delivered lists the channels that succeeded
+func (e *FanOutError) Delivered() []string {
+	var names []string
+	for _, r := range e.Results {
+		if r.Err == nil {
+			names = append(names, r.Channel)
+		}
+	}
+	return names
+}
+
+// FanOutNotifier sends every notification to all of its channels
+// concurrently. A failing or slow channel never keeps the others from
+// delivering; channels listed in the notification's DeliveredChannels are
+// skipped.
+type FanOutNotifier struct {
+	channels []FanOutChannel
+}
+
+func NewFanOutNotifier(channels ...FanOutChannel) *FanOutNotifier {
+	return &FanOutNotifier{channels: channels}
+}
+
+// Send delivers notification and returns one result per attempted channel
+func (f *FanOutNotifier) Send(ctx context.Context, notification EmailNotification) []ChannelResult {
+	var pending []FanOutChannel
+	for _, ch := range f.channels {
+		if !slices.Contains(notification.DeliveredChannels, ch.Name) {
+			pending = append(pending, ch)
+		}
+	}
+
+	results := make([]ChannelResult, len(pending))
+	var wg sync.WaitGroup
+	for i, ch := range pending {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := ch.Notifier.SendNotification(ctx, notification)
+			results[i] = ChannelResult{Channel: ch.Name, Err: err, Elapsed: time.Since(start)}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (f *FanOutNotifier) SendNotification(ctx context.Context, notification EmailNotification) error {
+	results := f.Send(ctx, notification)
+
+	failed := false
+	for _, r := range results {
+		if r.Err != nil {
+			failed = true
+			log.Printf("⚠️ Channel %s failed for %s %s->%s after %s: %v", r.Channel, notification.Email,
+				notification.CurrencyOrigin, notification.CurrencyDestination, r.Elapsed.Round(time.Millisecond), r.Err)
+		}
+	}
+	if !failed {
+		return nil
+	}
+	return &FanOutError{Results: results}
+}
+
+// deliveredChannels returns the channels of a fan-out error that succeeded,
+// or nil for any other error
+func deliveredChannels(err error) []string {
+	var fanOutErr *FanOutError
+	if errors.As(err, &fanOutErr) {
+		return fanOutErr.Delivered()
+	}
+	return nil
+}
+
+// redirectNotifier sends a copy of every notification to a fixed channel
+// and target, e.g. a team's Slack channel mirroring all alerts
+type redirectNotifier struct {
+	next    Notifier
+	channel string
+	target  string
+}
+
+func (r *redirectNotifier) SendNotification(ctx context.Context, notification EmailNotification) error {
+	notification.Channel = r.channel
+	notification.Target = r.target
+	if r.channel == domain.ChannelEmail {
+		notification.Email, notification.Target = r.target, ""
+	}
+	return r.next.SendNotification(ctx, notification)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/rates"
+)
+
+// RecordRates stores every rate of table in exchange_rates. The base itself
+// is stored with rate 1 so any pair can later be crossed from one snapshot.
+// Re-recording the same provider snapshot is a no-op.
+func (s *Server) RecordRates(ctx context.Context, table *rates.Table) (int, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	observedAt := time.Unix(table.Timestamp, 0).UTC()
+	if table.Timestamp == 0 {
+		observedAt = time.Now().UTC()
+	}
+
+	quotes := make(map[string]float64, len(table.Rates)+1)
+	for code, rate := range table.Rates {
+		if rate > 0 {
+			quotes[code] = rate
+		}
+	}
+	quotes[table.Base] = 1
+
+	placeholders := make([]string, 0, len(quotes))
+	args := make([]interface{}, 0, len(quotes)*5)
+	for code, rate := range quotes {
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?)")
+		args = append(args, table.Base, code, rate, table.Source, observedAt)
+	}
+
+	_, err := s.db.ExecContext(ctx, s.dialect.Rebind(`
+		INSERT INTO exchange_rates (base, quote, rate, provider, observed_at)
+		VALUES `+strings.Join(placeholders, ", ")+`
+		`+s.dialect.Upsert([]string{"base", "quote", "provider", "observed_at"}, "rate")+`
+	`), args...)
+	if err != nil {
+		return 0, fmt.Errorf("error recording exchange rates: %w", err)
+	}
+
+	return len(quotes), nil
+}
+
+// RateSeries returns the from->to rates observed since the given time,
+// oldest first, crossed from the stored snapshots
+func (s *Server) RateSeries(ctx context.Context, from, to string, since time.Time) ([]float64, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(`
+		SELECT b.rate / a.rate
+		FROM exchange_rates a
+		JOIN exchange_rates b
+		  ON b.base = a.base AND b.provider = a.provider AND b.observed_at = a.observed_at
+		WHERE a.quote = ? AND b.quote = ? AND a.observed_at >= ?
+		ORDER BY a.observed_at
+	`), from, to, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("error querying rate series: %w", err)
+	}
+	defer rows.Close()
+
+	var series []float64
+	for rows.Next() {
+		var rate float64
+		if err := rows.Scan(&rate); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		series = append(series, rate)
+	}
+	return series, rows.Err()
+}
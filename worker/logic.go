@@ -2,20 +2,21 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
-	"encoding/json"
+	"encoding/hex"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
+	"slices"
+	"time"
 
+	"github.com/joy-currency-conversion-GCP/shared/dialect"
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+	"github.com/joy-currency-conversion-GCP/shared/rates"
 	"github.com/joy-currency-conversion-GCP/worker/config"
 )
 
-var db *sql.DB
-var notifier Notifier
-
 // Notifier defines the contract for sending notifications
 type Notifier interface {
 	SendNotification(ctx context.Context, notification EmailNotification) error
@@ -23,162 +24,219 @@ type Notifier interface {
 
 // EmailNotification represents the data for sending an email
 type EmailNotification struct {
-	Email               string  `json:"email"`
-	CurrencyOrigin      string  `json:"currency_origin"`
-	CurrencyDestination string  `json:"currency_destination"`
-	CurrentRate         float64 `json:"current_rate"`
-	Threshold           float64 `json:"threshold"`
+	Email               string   `json:"email"`
+	CurrencyOrigin      string   `json:"currency_origin"`
+	CurrencyDestination string   `json:"currency_destination"`
+	CurrentRate         float64  `json:"current_rate"`
+	Threshold           float64  `json:"threshold"`
+	Direction           string   `json:"direction"`
+	Rule                string   `json:"rule"`
+	Window              string   `json:"window,omitempty"`
+	ObservedValue       float64  `json:"observed_value"`
+	PreviousRate        float64  `json:"previous_rate,omitempty"` // Rate seen on the previous check
+	Channel             string   `json:"-"`                       // How to deliver it, not part of the message
+	Target              string   `json:"-"`
+	DeliveredChannels   []string `json:"-"` // Fan-out channels that already got it
+	DeliveryID          string   `json:"-"` // Outbox id, the same on every retry
 }
 
-func InitDB(cfg *config.Config) error {
-	conn, err := cfg.DBConfig.Connect()
+// OpenDB connects to the configured database. Migrations are left to the
+// caller, as the migrate subcommand runs them itself.
+func OpenDB(cfg *config.Config) (*sql.DB, dialect.Dialect, error) {
+	d, err := dialect.For(cfg.DBConfig.Driver)
 	if err != nil {
-		return err
+		return nil, d, err
 	}
-	db = conn
-	return nil
-}
 
-func InitNotifierWithCloudFunction(cfg *config.Config) {
-	notifier = NewHTTPNotifier(cfg.FunctionURL)
+	db, err := cfg.DBConfig.Connect()
+	if err != nil {
+		return nil, d, err
+	}
+	return db, d, nil
 }
 
-func InitNotifier(cfg *config.Config) error {
-	projectID := os.Getenv("GCP_PROJECT_ID")
-	if projectID == "" {
-		return fmt.Errorf("GCP_PROJECT_ID not set")
+// NewRateProvider builds the providers of cfg.Rates, cached when
+// RATES_CACHE_TTL is set
+func NewRateProvider(cfg *config.Config) (rates.RateProvider, error) {
+	provider, err := rates.New(cfg.Rates)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RatesCacheTTL > 0 {
+		provider = rates.NewCache(provider, cfg.RatesCacheTTL)
 	}
+	return provider, nil
+}
 
-	pubsubNotifier, err := NewPubSubNotifier(projectID, cfg.PubSubTopicID)
+// NewNotifier builds the email notifier selected by cfg.Notifier next to
+// the Slack, Teams and (if enabled) signed webhook channels. Each alert goes
+// to its favorite's channel and to every cfg.FanOut channel, each retrying
+// transient failures on its own.
+func NewNotifier(cfg *config.Config) (Notifier, error) {
+	emailNotifier, err := newEmailNotifier(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create pubsub notifier: %w", err)
+		return nil, err
 	}
 
-	notifier = pubsubNotifier
-	return nil
-}
+	router := channelRouter{
+		domain.ChannelEmail: emailNotifier,
+		domain.ChannelSlack: NewSlackNotifier(cfg.NotifyTimeout),
+		domain.ChannelTeams: NewTeamsNotifier(cfg.NotifyTimeout),
+	}
+	if cfg.WebhooksEnabled {
+		router[domain.ChannelWebhook] = NewWebhookNotifier(cfg.WebhookSecret, cfg.NotifyTimeout)
+	}
+
+	policy := retryPolicyFromConfig(cfg)
+	channels := []FanOutChannel{{Name: "primary", Notifier: NewRetryingNotifier(router, policy)}}
+	for _, extra := range cfg.FanOut {
+		if _, ok := router[extra.Channel]; !ok {
+			return nil, fmt.Errorf("fan-out channel %q is unknown or disabled", extra.Channel)
+		}
+		name := fanOutName(extra)
+		if slices.ContainsFunc(channels, func(ch FanOutChannel) bool { return ch.Name == name }) {
+			log.Printf("⚠️ Ignoring repeated fan-out channel %s", extra.Channel)
+			continue
+		}
+		channels = append(channels, FanOutChannel{
+			Name:     name,
+			Notifier: NewRetryingNotifier(&redirectNotifier{next: router, channel: extra.Channel, target: extra.Target}, policy),
+		})
+	}
+	if len(channels) > 1 {
+		log.Printf("📣 Fanning out every alert to %d channels", len(channels))
+	}
 
-type FavoriteConversion struct {
-	ID                  int64
-	Email               string
-	CurrencyOrigin      string
-	CurrencyDestination string
-	Threshold           float64
+	return NewFanOutNotifier(channels...), nil
 }
 
-type ExchangeRatesResponse struct {
-	Success   bool               `json:"success"`
-	Timestamp int64              `json:"timestamp"`
-	Base      string             `json:"base"`
-	Date      string             `json:"date"`
-	Rates     map[string]float64 `json:"rates"`
+// fanOutName names an extra channel after its destination rather than its
+// position, since the names of the channels an alert already reached are
+// stored with it and NOTIFY_FANOUT may change before it is retried
+func fanOutName(target config.FanOutTarget) string {
+	sum := sha256.Sum256([]byte(target.Channel + "|" + target.Target))
+	return target.Channel + ":" + hex.EncodeToString(sum[:4])
 }
 
-func GetAllFavorites() ([]FavoriteConversion, error) {
-	if db == nil {
-		return nil, fmt.Errorf("database not initialized")
+func newEmailNotifier(cfg *config.Config) (Notifier, error) {
+	switch cfg.Notifier {
+	case config.NotifierHTTP:
+		return NewHTTPNotifier(cfg.FunctionURL, cfg.NotifyTimeout), nil
+	case config.NotifierSMTP:
+		smtpNotifier, err := NewSMTPNotifier(cfg.SMTP, cfg.NotifyTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create smtp notifier: %w", err)
+		}
+		log.Printf("📧 Sending notifications by SMTP through %s:%d", cfg.SMTP.Host, cfg.SMTP.Port)
+		return smtpNotifier, nil
 	}
 
-	rows, err := db.Query(`
-		SELECT id, email, currency_origin, currency_destination, threshold 
-		FROM favorite_conversions
-	`)
-	if err != nil {
-		return nil, fmt.Errorf("error querying favorites: %w", err)
+	projectID := os.Getenv("GCP_PROJECT_ID")
+	if projectID == "" {
+		return nil, fmt.Errorf("GCP_PROJECT_ID not set")
 	}
-	defer rows.Close()
 
-	var favorites []FavoriteConversion
-	for rows.Next() {
-		var fav FavoriteConversion
-		err := rows.Scan(&fav.ID, &fav.Email, &fav.CurrencyOrigin, &fav.CurrencyDestination, &fav.Threshold)
-		if err != nil {
-			return nil, fmt.Errorf("error scanning row: %w", err)
-		}
-		favorites = append(favorites, fav)
+	pubsubNotifier, err := NewPubSubNotifier(projectID, cfg.PubSubTopicID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pubsub notifier: %w", err)
 	}
-
-	return favorites, nil
+	return pubsubNotifier, nil
 }
 
-func GetExchangeRate(apiKey, base, target string) (float64, error) {
-	url := fmt.Sprintf("https://api.exchangeratesapi.io/v1/latest?access_key=%s&base=%s", apiKey, base)
-
-	resp, err := http.Get(url)
+// CheckThresholdsAndNotify fetches the EUR rate table once, records it in the
+// rate history and evaluates every favorite against it, crossing through
+// EUR for pairs with another origin. policy decides whether a triggered
+// favorite may notify again.
+func (s *Server) CheckThresholdsAndNotify(ctx context.Context, policy AlertPolicy) error {
+	favorites, err := s.favorites.ListAll(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to call exchange rates API: %w", err)
+		return fmt.Errorf("error getting favorites: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("exchange rates API returned status %d: %s", resp.StatusCode, string(body))
-	}
+	log.Printf("Found %d favorite conversions to check", len(favorites))
 
-	body, err := io.ReadAll(resp.Body)
+	table, err := s.rates.Latest(ctx, domain.RatesBase)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read response body: %w", err)
+		return fmt.Errorf("error getting %s rates: %w", domain.RatesBase, err)
 	}
 
-	var exchangeResp ExchangeRatesResponse
-	if err := json.Unmarshal(body, &exchangeResp); err != nil {
-		return 0, fmt.Errorf("failed to parse JSON response: %w", err)
+	if recorded, err := s.RecordRates(ctx, table); err != nil {
+		log.Printf("Error recording %s rates: %v", domain.RatesBase, err)
+	} else {
+		log.Printf("Recorded %d %s rates from %s", recorded, domain.RatesBase, table.Source)
 	}
 
-	if !exchangeResp.Success {
-		return 0, fmt.Errorf("exchange rates API returned success=false")
-	}
-
-	rate, exists := exchangeResp.Rates[target]
-	if !exists {
-		return 0, fmt.Errorf("%s rate not found in API response", target)
+	for _, fav := range favorites {
+		s.checkFavorite(ctx, table, fav, policy)
 	}
 
-	return rate, nil
+	return nil
 }
 
-func CheckThresholdsAndNotify(ctx context.Context, apiKey string) error {
-	favorites, err := GetAllFavorites()
+func (s *Server) checkFavorite(ctx context.Context, table *rates.Table, fav domain.FavoriteConversion, policy AlertPolicy) {
+	rate, err := table.Rate(fav.CurrencyOrigin, fav.CurrencyDestination)
 	if err != nil {
-		return fmt.Errorf("error getting favorites: %w", err)
+		log.Printf("Error getting rate for %s->%s (email: %s): %v",
+			fav.CurrencyOrigin, fav.CurrencyDestination, fav.Email, err)
+		return
 	}
 
-	log.Printf("Found %d favorite conversions to check", len(favorites))
+	result, err := s.evaluateRule(ctx, fav, rate)
+	if err != nil {
+		log.Printf("Error evaluating %s rule of favorite %d: %v", fav.RuleType, fav.ID, err)
+		return
+	}
+
+	log.Printf("Checking favorite %d (%s %s->%s): rate=%.2f, %s %s value=%.4f threshold=%.4f (sources: %v)",
+		fav.ID, fav.Email, fav.CurrencyOrigin, fav.CurrencyDestination, rate, fav.RuleType, fav.Direction,
+		result.Value, fav.Threshold, table.SourcesFor(fav.CurrencyOrigin, fav.CurrencyDestination))
 
-	maxChecks := 1 // For avoid rate limit in the free tier
-	if len(favorites) > maxChecks {
-		log.Printf("⚠️ Limiting checks to %d favorites (found %d total)", maxChecks, len(favorites))
-		favorites = favorites[:maxChecks]
+	if err := s.favorites.SetLastRate(ctx, fav.ID, rate); err != nil {
+		log.Printf("Error saving last rate of favorite %d: %v", fav.ID, err)
 	}
 
-	for _, fav := range favorites {
-		rate, err := GetExchangeRate(apiKey, fav.CurrencyOrigin, fav.CurrencyDestination)
-		if err != nil {
-			log.Printf("Error getting rate for %s->%s (email: %s): %v", 
-				fav.CurrencyOrigin, fav.CurrencyDestination, fav.Email, err)
-			continue
+	if !fav.Armed {
+		if !result.Rearms(policy.RearmBand) {
+			return
+		}
+		if err := s.favorites.Rearm(ctx, fav.ID); err != nil {
+			log.Printf("Error re-arming favorite %d: %v", fav.ID, err)
+			return
 		}
+		log.Printf("🔔 Favorite %d re-armed (value %.4f back past %.4f)", fav.ID, result.Value, result.Bound)
+	}
 
-		log.Printf("Checking %s: rate=%.2f, threshold=%.2f", fav.Email, rate, fav.Threshold)
+	if !result.Triggered {
+		return
+	}
 
-		if rate >= fav.Threshold {
-			notification := EmailNotification{
-				Email:               fav.Email,
-				CurrencyOrigin:      fav.CurrencyOrigin,
-				CurrencyDestination: fav.CurrencyDestination,
-				CurrentRate:         rate,
-				Threshold:           fav.Threshold,
-			}
+	now := time.Now()
+	if policy.inCooldown(fav, now) {
+		log.Printf("Favorite %d triggered but is cooling down (last notified %s)",
+			fav.ID, fav.LastNotifiedAt.Time.Format(time.RFC3339))
+		return
+	}
 
-			if err := notifier.SendNotification(ctx, notification); err != nil {
-				log.Printf("Error sending notification to %s: %v", fav.Email, err)
-				continue
-			}
+	notification := EmailNotification{
+		Email:               fav.Email,
+		CurrencyOrigin:      fav.CurrencyOrigin,
+		CurrencyDestination: fav.CurrencyDestination,
+		CurrentRate:         rate,
+		Threshold:           fav.Threshold,
+		Direction:           fav.Direction,
+		Rule:                fav.RuleType,
+		Window:              domain.FormatWindow(fav.Window),
+		ObservedValue:       result.Value,
+		PreviousRate:        fav.LastRate.Float64,
+		Channel:             fav.Channel,
+		Target:              fav.Target,
+	}
 
-			log.Printf("✅ Notification sent to %s (rate %.2f >= threshold %.2f)", 
-				fav.Email, rate, fav.Threshold)
-		}
+	if err := s.QueueNotification(ctx, fav.ID, rate, now, notification); err != nil {
+		log.Printf("Error queueing notification for %s: %v", fav.Email, err)
+		return
 	}
 
-	return nil
-}
\ No newline at end of file
+	log.Printf("📨 Notification queued for %s (%s %s: %.4f vs threshold %.4f)",
+		fav.Email, fav.RuleType, fav.Direction, result.Value, fav.Threshold)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/joy-currency-conversion-GCP/worker/config"
+)
+
+func TestFanOutNameIgnoresPosition(t *testing.T) {
+	slack := config.FanOutTarget{Channel: "slack", Target: "https://hooks.slack.com/services/T0/B0/x"}
+	teams := config.FanOutTarget{Channel: "teams", Target: "https://example.webhook.office.com/webhookb2/x"}
+
+	before := []string{fanOutName(slack), fanOutName(teams)}
+	after := []string{fanOutName(teams), fanOutName(slack)}
+	if before[0] != after[1] || before[1] != after[0] {
+		t.Errorf("names changed with the order: %v then %v", before, after)
+	}
+
+	other := config.FanOutTarget{Channel: "slack", Target: "https://hooks.slack.com/services/T0/B0/y"}
+	if fanOutName(other) == fanOutName(slack) {
+		t.Errorf("two slack targets share the name %s", fanOutName(slack))
+	}
+	if len(fanOutName(slack)) > 32 {
+		t.Errorf("name %s is too long for delivered_channels", fanOutName(slack))
+	}
+}
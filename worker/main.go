@@ -1,44 +1,98 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/joy-currency-conversion-GCP/shared/favorites"
+	"github.com/joy-currency-conversion-GCP/shared/schema"
 	"github.com/joy-currency-conversion-GCP/worker/config"
 )
 
-var appConfig *config.Config
-
 func main() {
-	appConfig = config.Load()
+	// Migrations only need the database, so they run without the secrets
+	// the rest of the worker requires
+	migrating := len(os.Args) > 1 && os.Args[1] == "migrate"
+	var cfg *config.Config
+	if migrating {
+		cfg = config.LoadForMigrate()
+	} else {
+		cfg = config.Load()
+	}
 
-	if err := InitDB(appConfig); err != nil {
+	db, d, err := OpenDB(cfg)
+	if err != nil {
 		log.Fatalf("failed to initialize database: %v", err)
 	}
+	repo := favorites.NewSQL(db, d)
+	outbox := NewSQLOutbox(db, d)
+
+	// The migrate subcommand manages the schema itself
+	if cfg.MigrateOnStart && !migrating {
+		if err := schema.Migrate(context.Background(), db, d.Driver, cfg.MigrateLockTimeout); err != nil {
+			log.Fatalf("failed to migrate database: %v", err)
+		}
+	}
+
+	if len(os.Args) > 1 {
+		srv := NewServer(cfg, repo, outbox, nil, nil, db, d)
+		if err := srv.runCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalf("❌ %s failed: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	provider, err := NewRateProvider(cfg)
+	if err != nil {
+		log.Fatalf("failed to initialize rates provider: %v", err)
+	}
 
-	if err := InitNotifier(appConfig); err != nil {
+	notifier, err := NewNotifier(cfg)
+	if err != nil {
 		log.Fatalf("failed to initialize notifier: %v", err)
 	}
 
-	http.HandleFunc("/check-thresholds", checkThresholdsHandler)
-	http.HandleFunc("/health", healthHandler)
-	http.HandleFunc("/delete-all-favorites", deleteAllFavoritesHandler)
+	srv := NewServer(cfg, repo, outbox, provider, notifier, db, d)
+
+	if cfg.OutboxDispatchInterval > 0 {
+		go srv.RunOutboxDispatcher(context.Background(), outboxPolicyFromConfig(cfg), cfg.OutboxDispatchInterval)
+	}
 
-	addr := ":" + appConfig.Port
-	log.Printf("🚀 Worker running on %s (environment: %s)", addr, appConfig.Environment)
+	addr := ":" + cfg.Port
+	log.Printf("🚀 Worker running on %s (environment: %s)", addr, cfg.Environment)
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	if err := http.ListenAndServe(addr, srv); err != nil {
 		log.Fatalf("❌ Error starting server: %v", err)
 	}
 }
 
+// runCommand runs a CLI subcommand instead of the HTTP server
+func (s *Server) runCommand(name string, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch name {
+	case "backfill":
+		return s.runBackfill(ctx, args)
+	case "migrate":
+		return s.runMigrate(ctx, args)
+	default:
+		return fmt.Errorf("unknown command %q (available: backfill, migrate)", name)
+	}
+}
+
 type CheckResponse struct {
 	Message string `json:"message"`
 	Status  string `json:"status"`
 }
 
-func checkThresholdsHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) checkThresholdsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -46,7 +100,14 @@ func checkThresholdsHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("📊 Starting threshold check...")
 
-	if err := CheckThresholdsAndNotify(r.Context(), appConfig.APIKey); err != nil {
+	err := s.CheckThresholdsAndNotify(r.Context(), alertPolicyFromConfig(s.config))
+
+	// Deliver what this run queued while the request still holds the CPU
+	if drainErr := s.DrainOutbox(r.Context(), outboxPolicyFromConfig(s.config)); drainErr != nil {
+		log.Printf("Error dispatching outbox: %v", drainErr)
+	}
+
+	if err != nil {
 		log.Printf("Error checking thresholds: %v", err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -64,28 +125,50 @@ func checkThresholdsHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (s *Server) dispatchNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.DrainOutbox(r.Context(), outboxPolicyFromConfig(s.config)); err != nil {
+		log.Printf("Error dispatching outbox: %v", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(CheckResponse{
+			Message: "error dispatching notifications",
+			Status:  "error",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CheckResponse{
+		Message: "notifications dispatched",
+		Status:  "success",
+	})
+}
+
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
 
-func deleteAllFavoritesHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) deleteAllFavoritesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	result, err := db.Exec("DELETE FROM favorite_conversions")
+	rowsAffected, err := s.favorites.DeleteAll(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "all favorites deleted",
+		"message":       "all favorites deleted",
 		"rows_affected": rowsAffected,
 	})
-}
\ No newline at end of file
+}
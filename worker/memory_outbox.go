@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+	"github.com/joy-currency-conversion-GCP/shared/favorites"
+)
+
+// MemoryOutbox keeps notifications in memory next to an in-memory favorites
+// repository, for tests
+type MemoryOutbox struct {
+	mu        sync.Mutex
+	favorites favorites.Repository
+	messages  []memoryOutboxEntry
+}
+
+type memoryOutboxEntry struct {
+	OutboxMessage
+	status        string
+	nextAttemptAt time.Time
+}
+
+func NewMemoryOutbox(repo favorites.Repository) *MemoryOutbox {
+	return &MemoryOutbox{favorites: repo}
+}
+
+func (o *MemoryOutbox) Queue(ctx context.Context, rate float64, at time.Time, msg OutboxMessage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	_, err := o.favorites.Update(ctx, msg.FavoriteID, func(fav *domain.FavoriteConversion) error {
+		fav.Armed = false
+		fav.LastNotifiedAt = sql.NullTime{Time: at.UTC(), Valid: true}
+		fav.LastNotifiedRate = sql.NullFloat64{Float64: rate, Valid: true}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	msg.ID = int64(len(o.messages) + 1)
+	o.messages = append(o.messages, memoryOutboxEntry{OutboxMessage: msg, status: OutboxPending, nextAttemptAt: at})
+	return nil
+}
+
+func (o *MemoryOutbox) Claim(ctx context.Context, now, leaseEnd time.Time, limit, maxAttempts int) ([]OutboxMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var batch []OutboxMessage
+	for i := range o.messages {
+		entry := &o.messages[i]
+		if entry.nextAttemptAt.After(now) {
+			continue
+		}
+		switch {
+		case entry.status == OutboxSending && entry.Attempts >= maxAttempts:
+			entry.status = OutboxFailed
+		case (entry.status == OutboxPending || entry.status == OutboxSending) && len(batch) < limit:
+			entry.status, entry.nextAttemptAt = OutboxSending, leaseEnd
+			entry.Attempts++
+			batch = append(batch, entry.OutboxMessage)
+		}
+	}
+	return batch, nil
+}
+
+// Record keeps the same lease check as SQLOutbox.Record
+func (o *MemoryOutbox) Record(ctx context.Context, msg OutboxMessage, result OutboxResult) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if msg.ID < 1 || msg.ID > int64(len(o.messages)) {
+		return ErrLeaseLost
+	}
+	entry := &o.messages[msg.ID-1]
+	if entry.status != OutboxSending || entry.Attempts != msg.Attempts {
+		return ErrLeaseLost
+	}
+
+	entry.status = result.Status
+	if result.Status != OutboxDelivered {
+		entry.Delivered = result.Delivered
+	}
+	if result.Status == OutboxPending {
+		entry.nextAttemptAt = result.NextAttemptAt
+	}
+	return nil
+}
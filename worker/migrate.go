@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/schema"
+)
+
+// runMigrate implements the "migrate" subcommand:
+//
+//	migrate [up]       apply every pending migration
+//	migrate down [N]   revert the last N migrations (default 1)
+//	migrate to V       migrate up or down to version V, 0 reverting them all
+//	migrate status     list migrations and when they were applied
+func (s *Server) runMigrate(ctx context.Context, args []string) error {
+	m, err := schema.NewMigrator(s.db, s.dialect.Driver, s.config.MigrateLockTimeout)
+	if err != nil {
+		return err
+	}
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "up":
+		applied, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("✅ Applied %d migrations", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("down takes a positive number of steps, got %q", args[1])
+			}
+		}
+		reverted, err := m.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		log.Printf("✅ Reverted %d migrations", reverted)
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("to requires a version")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		changed, err := m.To(ctx, version)
+		if err != nil {
+			return err
+		}
+		log.Printf("✅ Ran %d migrations to reach version %d", changed, version)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, applied)
+		}
+	default:
+		return fmt.Errorf("unknown migrate action %q (available: up, down, to, status)", action)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sharedconfig "github.com/joy-currency-conversion-GCP/shared/config"
+	"github.com/joy-currency-conversion-GCP/shared/dialect"
+	"github.com/joy-currency-conversion-GCP/shared/favorites"
+	"github.com/joy-currency-conversion-GCP/worker/config"
+)
+
+func TestRunMigrate(t *testing.T) {
+	dbConfig := sharedconfig.DatabaseConfig{Driver: dialect.SQLite, Path: filepath.Join(t.TempDir(), "worker.db")}
+	db, err := dbConfig.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	d, _ := dialect.For(dialect.SQLite)
+
+	cfg := &config.Config{}
+	cfg.MigrateLockTimeout = time.Second
+	srv := NewServer(cfg, favorites.NewSQL(db, d), NewSQLOutbox(db, d), nil, nil, db, d)
+	ctx := context.Background()
+
+	tableCount := func() int {
+		var count int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'favorite_conversions'`).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	if err := srv.runMigrate(ctx, nil); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if tableCount() != 1 {
+		t.Fatal("migrate did not create favorite_conversions")
+	}
+	if err := srv.runMigrate(ctx, []string{"status"}); err != nil {
+		t.Fatalf("migrate status: %v", err)
+	}
+	if err := srv.runMigrate(ctx, []string{"to", "0"}); err != nil {
+		t.Fatalf("migrate to 0: %v", err)
+	}
+	if tableCount() != 0 {
+		t.Fatal("migrate to 0 left favorite_conversions")
+	}
+
+	invalid := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"to", "999"}, "unknown migration version 999"},
+		{[]string{"to"}, "requires a version"},
+		{[]string{"to", "-1"}, "invalid version"},
+		{[]string{"down", "0"}, "positive number of steps"},
+		{[]string{"sideways"}, "unknown migrate action"},
+	}
+	for _, tt := range invalid {
+		if err := srv.runMigrate(ctx, tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("migrate %v = %v, want an error containing %q", tt.args, err, tt.want)
+		}
+	}
+}
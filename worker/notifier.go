@@ -6,15 +6,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 type HTTPNotifier struct {
 	functionURL string
+	client      *http.Client
 }
 
-func NewHTTPNotifier(functionURL string) *HTTPNotifier {
+func NewHTTPNotifier(functionURL string, timeout time.Duration) *HTTPNotifier {
 	return &HTTPNotifier{
 		functionURL: functionURL,
+		client:      &http.Client{Timeout: timeout},
 	}
 }
 
@@ -31,15 +34,14 @@ func (n *HTTPNotifier) SendNotification(ctx context.Context, notification EmailN
 
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := n.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("error calling cloud function: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("cloud function returned status %d", resp.StatusCode)
+		return fmt.Errorf("error calling cloud function: %w", &StatusError{StatusCode: resp.StatusCode})
 	}
 
 	return nil
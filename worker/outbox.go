@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/worker/config"
+)
+
+// Outbox statuses
+const (
+	OutboxPending   = "pending"
+	OutboxSending   = "sending" // Claimed by a dispatcher until its lease expires
+	OutboxDelivered = "delivered"
+	OutboxFailed    = "failed"
+)
+
+// OutboxPolicy controls how queued notifications are retried. A message is
+// retried after BaseDelay, doubling up to MaxDelay, and given up on (marked
+// failed) after MaxAttempts. A dispatcher holds the batch it claims for
+// Lease.
+type OutboxPolicy struct {
+	BatchSize   int
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Lease       time.Duration
+}
+
+func outboxPolicyFromConfig(cfg *config.Config) OutboxPolicy {
+	return OutboxPolicy{
+		BatchSize:   50,
+		MaxAttempts: cfg.OutboxMaxAttempts,
+		BaseDelay:   cfg.OutboxRetryBaseDelay,
+		MaxDelay:    cfg.OutboxRetryMaxDelay,
+		Lease:       cfg.OutboxLease,
+	}
+}
+
+// backoff returns the delay before the next attempt after attempts failures
+func (p OutboxPolicy) backoff(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// OutboxMessage is a queued notification. Payload is the JSON of its
+// EmailNotification, without the delivery fields kept alongside.
+type OutboxMessage struct {
+	ID         int64
+	FavoriteID int64
+	Payload    string
+	Channel    string
+	Target     string
+	Delivered  []string // Fan-out channels already sent
+	Attempts   int      // Claims so far, including the current one
+}
+
+// OutboxResult is the outcome of sending a claimed message
+type OutboxResult struct {
+	Status        string
+	NextAttemptAt time.Time // When a pending message is retried
+	DeliveredAt   time.Time
+	LastError     string
+	Delivered     []string
+}
+
+// OutboxStore keeps notifications until they are delivered, with SQL and
+// in-memory implementations
+type OutboxStore interface {
+	// Queue disarms the favorite, remembers that it notified at rate and
+	// stores msg as pending, all or nothing, so an alert is never lost nor
+	// recorded as sent without a message to deliver
+	Queue(ctx context.Context, rate float64, at time.Time, msg OutboxMessage) error
+
+	// Claim leases up to limit messages due at now to the caller until
+	// leaseEnd, counting an attempt for each; others can't claim them in the
+	// meantime. Messages whose lease expired maxAttempts times without a
+	// result, e.g. because they crash the dispatcher, are marked failed.
+	Claim(ctx context.Context, now, leaseEnd time.Time, limit, maxAttempts int) ([]OutboxMessage, error)
+
+	// Record stores the result of sending msg. It returns ErrLeaseLost, and
+	// stores nothing, if msg was claimed again since.
+	Record(ctx context.Context, msg OutboxMessage, result OutboxResult) error
+}
+
+// ErrLeaseLost means another dispatcher claimed a message after the lease
+// of the one sending it expired
+var ErrLeaseLost = errors.New("outbox lease lost")
+
+// QueueNotification disarms the favorite and queues its notification
+func (s *Server) QueueNotification(ctx context.Context, favoriteID int64, rate float64, at time.Time, notification EmailNotification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("error marshaling notification: %w", err)
+	}
+
+	return s.outbox.Queue(ctx, rate, at, OutboxMessage{
+		FavoriteID: favoriteID,
+		Payload:    string(payload),
+		Channel:    notification.Channel,
+		Target:     notification.Target,
+	})
+}
+
+// DispatchOutbox publishes one batch of due notifications. The batch is
+// claimed first and then sent, so no lock is held while a channel is slow.
+// Messages still unsent when the lease expires, or whose dispatcher crashed,
+// are claimed again, so delivery is at-least-once.
+func (s *Server) DispatchOutbox(ctx context.Context, policy OutboxPolicy) (delivered, failed int, err error) {
+	now := time.Now().UTC()
+	leaseEnd := now.Add(policy.Lease)
+	batch, err := s.outbox.Claim(ctx, now, leaseEnd, policy.BatchSize, policy.MaxAttempts)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, msg := range batch {
+		if time.Now().After(leaseEnd) {
+			log.Printf("⏳ Outbox lease expired, leaving %d notifications to the next dispatch", len(batch)-delivered-failed)
+			break
+		}
+
+		sendErr := s.sendOutboxMessage(ctx, msg)
+		err := s.outbox.Record(ctx, msg, outboxResult(msg, sendErr, policy))
+		if errors.Is(err, ErrLeaseLost) {
+			log.Printf("⏳ Outbox lease of notification %d expired while sending, leaving it to the dispatcher that claimed it", msg.ID)
+			continue
+		}
+		if err != nil {
+			return delivered, failed, fmt.Errorf("error updating outbox row %d: %w", msg.ID, err)
+		}
+		if sendErr == nil {
+			delivered++
+		} else {
+			failed++
+		}
+	}
+	return delivered, failed, nil
+}
+
+// outboxResult decides what becomes of a message after sending it
+func outboxResult(msg OutboxMessage, sendErr error, policy OutboxPolicy) OutboxResult {
+	switch {
+	case sendErr == nil:
+		return OutboxResult{Status: OutboxDelivered, DeliveredAt: time.Now().UTC()}
+	case msg.Attempts >= policy.MaxAttempts:
+		log.Printf("❌ Giving up on notification %d after %d attempts: %v", msg.ID, msg.Attempts, sendErr)
+		return OutboxResult{Status: OutboxFailed, LastError: sendErr.Error(), Delivered: msg.deliveredAfter(sendErr)}
+	default:
+		retryAt := time.Now().Add(policy.backoff(msg.Attempts))
+		log.Printf("⚠️ Notification %d failed (attempt %d/%d), retrying at %s: %v",
+			msg.ID, msg.Attempts, policy.MaxAttempts, retryAt.Format(time.RFC3339), sendErr)
+		return OutboxResult{
+			Status:        OutboxPending,
+			NextAttemptAt: retryAt.UTC(),
+			LastError:     sendErr.Error(),
+			Delivered:     msg.deliveredAfter(sendErr),
+		}
+	}
+}
+
+// deliveredAfter adds the fan-out channels that succeeded despite sendErr to
+// the ones already delivered, so retries only go to the failed ones
+func (msg OutboxMessage) deliveredAfter(sendErr error) []string {
+	return append(append([]string(nil), msg.Delivered...), deliveredChannels(sendErr)...)
+}
+
+func (s *Server) sendOutboxMessage(ctx context.Context, msg OutboxMessage) error {
+	var notification EmailNotification
+	if err := json.Unmarshal([]byte(msg.Payload), &notification); err != nil {
+		return fmt.Errorf("error decoding outbox payload: %w", err)
+	}
+	notification.Channel = msg.Channel
+	notification.Target = msg.Target
+	notification.DeliveryID = strconv.FormatInt(msg.ID, 10)
+	notification.DeliveredChannels = msg.Delivered
+	return s.notifier.SendNotification(ctx, notification)
+}
+
+// DrainOutbox dispatches batches until no due notification is left or a
+// batch makes no progress
+func (s *Server) DrainOutbox(ctx context.Context, policy OutboxPolicy) error {
+	for {
+		delivered, failed, err := s.DispatchOutbox(ctx, policy)
+		if err != nil {
+			return err
+		}
+		if delivered+failed > 0 {
+			log.Printf("📬 Outbox batch: %d delivered, %d failed", delivered, failed)
+		}
+		if delivered == 0 || delivered+failed < policy.BatchSize {
+			return nil
+		}
+	}
+}
+
+// RunOutboxDispatcher drains the outbox every interval until ctx is done
+func (s *Server) RunOutboxDispatcher(ctx context.Context, policy OutboxPolicy, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.DrainOutbox(ctx, policy); err != nil {
+				log.Printf("Error dispatching outbox: %v", err)
+			}
+		}
+	}
+}
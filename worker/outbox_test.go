@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sharedconfig "github.com/joy-currency-conversion-GCP/shared/config"
+	"github.com/joy-currency-conversion-GCP/shared/dialect"
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+	"github.com/joy-currency-conversion-GCP/shared/favorites"
+	"github.com/joy-currency-conversion-GCP/shared/schema"
+)
+
+// outboxes runs test against both outbox stores, each with one favorite
+// whose id is passed along
+func outboxes(t *testing.T, test func(t *testing.T, outbox OutboxStore, favoriteID int64)) {
+	create := func(t *testing.T, repo favorites.Repository) int64 {
+		id, err := repo.Create(context.Background(), &domain.FavoriteConversion{
+			Email: "ana@example.com", CurrencyOrigin: "EUR", CurrencyDestination: "USD", Threshold: 1,
+			Direction: domain.DirectionAbove, RuleType: domain.RuleThreshold, Channel: domain.ChannelEmail,
+		})
+		if err != nil {
+			t.Fatalf("create favorite: %v", err)
+		}
+		return id
+	}
+
+	t.Run("memory", func(t *testing.T) {
+		repo := favorites.NewMemory()
+		test(t, NewMemoryOutbox(repo), create(t, repo))
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		cfg := sharedconfig.DatabaseConfig{Driver: dialect.SQLite, Path: filepath.Join(t.TempDir(), "outbox.db")}
+		db, err := cfg.Connect()
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		if err := schema.Migrate(context.Background(), db, dialect.SQLite, time.Minute); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+		d, _ := dialect.For(dialect.SQLite)
+		test(t, NewSQLOutbox(db, d), create(t, favorites.NewSQL(db, d)))
+	})
+}
+
+func claim(t *testing.T, outbox OutboxStore, now time.Time, maxAttempts int) []OutboxMessage {
+	t.Helper()
+	batch, err := outbox.Claim(context.Background(), now, now.Add(time.Minute), 10, maxAttempts)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	return batch
+}
+
+func TestOutboxRecordNeedsTheLease(t *testing.T) {
+	outboxes(t, func(t *testing.T, outbox OutboxStore, favoriteID int64) {
+		ctx := context.Background()
+		now := time.Now().UTC()
+		if err := outbox.Queue(ctx, 1.1, now, OutboxMessage{FavoriteID: favoriteID, Payload: "{}", Channel: domain.ChannelEmail}); err != nil {
+			t.Fatalf("queue: %v", err)
+		}
+
+		first := claim(t, outbox, now, 5)
+		if len(first) != 1 || first[0].Attempts != 1 {
+			t.Fatalf("first claim got %+v, want one message on its first attempt", first)
+		}
+		if again := claim(t, outbox, now, 5); len(again) != 0 {
+			t.Fatalf("claimed a leased message again: %+v", again)
+		}
+
+		// The first dispatcher stalls past its lease and a second one takes over
+		second := claim(t, outbox, now.Add(2*time.Minute), 5)
+		if len(second) != 1 || second[0].Attempts != 2 {
+			t.Fatalf("second claim got %+v, want the message on its second attempt", second)
+		}
+		if err := outbox.Record(ctx, second[0], OutboxResult{Status: OutboxDelivered, DeliveredAt: now}); err != nil {
+			t.Fatalf("record second: %v", err)
+		}
+
+		// The stalled dispatcher must not set it back to pending
+		err := outbox.Record(ctx, first[0], OutboxResult{Status: OutboxPending, NextAttemptAt: now, LastError: "timeout"})
+		if !errors.Is(err, ErrLeaseLost) {
+			t.Fatalf("record first: got %v, want ErrLeaseLost", err)
+		}
+		if again := claim(t, outbox, now.Add(time.Hour), 5); len(again) != 0 {
+			t.Errorf("a delivered message was claimed again: %+v", again)
+		}
+	})
+}
+
+func TestOutboxFailsMessagesThatNeverReportBack(t *testing.T) {
+	outboxes(t, func(t *testing.T, outbox OutboxStore, favoriteID int64) {
+		now := time.Now().UTC()
+		if err := outbox.Queue(context.Background(), 1.1, now, OutboxMessage{FavoriteID: favoriteID, Payload: "{}", Channel: domain.ChannelEmail}); err != nil {
+			t.Fatalf("queue: %v", err)
+		}
+
+		// Each dispatcher crashes while sending, leaving the lease to expire
+		for attempt := 1; attempt <= 3; attempt++ {
+			batch := claim(t, outbox, now, 3)
+			if len(batch) != 1 || batch[0].Attempts != attempt {
+				t.Fatalf("claim %d got %+v", attempt, batch)
+			}
+			now = now.Add(2 * time.Minute)
+		}
+
+		if batch := claim(t, outbox, now, 3); len(batch) != 0 {
+			t.Errorf("claimed %+v after 3 attempts, want it failed", batch)
+		}
+		if batch := claim(t, outbox, now.Add(time.Hour), 3); len(batch) != 0 {
+			t.Errorf("a failed message was claimed again: %+v", batch)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/textproto"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/joy-currency-conversion-GCP/worker/config"
+)
+
+// StatusError is returned by notifiers that talk HTTP when the endpoint
+// answers with an unexpected status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("notification endpoint returned status %d", e.StatusCode)
+}
+
+// RetryPolicy controls how a RetryingNotifier retries failed sends
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts, including the first one
+	BaseDelay   time.Duration // Delay before the first retry, doubled on each retry
+	MaxDelay    time.Duration // Upper bound of a single delay
+	Jitter      float64       // Fraction of each delay that is randomized, 0 to 1
+}
+
+func retryPolicyFromConfig(cfg *config.Config) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: cfg.NotifyMaxAttempts,
+		BaseDelay:   cfg.NotifyRetryBaseDelay,
+		MaxDelay:    cfg.NotifyRetryMaxDelay,
+		Jitter:      cfg.NotifyRetryJitter,
+	}
+}
+
+// RetryingNotifier retries sends of the wrapped notifier that fail with a
+// retryable error, backing off exponentially with jitter
+type RetryingNotifier struct {
+	next   Notifier
+	policy RetryPolicy
+}
+
+func NewRetryingNotifier(next Notifier, policy RetryPolicy) *RetryingNotifier {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	policy.Jitter = min(max(policy.Jitter, 0), 1)
+	return &RetryingNotifier{next: next, policy: policy}
+}
+
+func (n *RetryingNotifier) SendNotification(ctx context.Context, notification EmailNotification) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = n.next.SendNotification(ctx, notification)
+		if err == nil {
+			return nil
+		}
+		if attempt >= n.policy.MaxAttempts || !isRetryable(ctx, err) {
+			return err
+		}
+
+		delay := n.policy.delay(attempt)
+		// Don't start a wait the caller's deadline won't let us finish
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return fmt.Errorf("giving up after %d attempts, deadline too close: %w", attempt, err)
+		}
+
+		log.Printf("⚠️ Notification to %s failed (attempt %d/%d), retrying in %s: %v",
+			notification.Email, attempt, n.policy.MaxAttempts, delay.Round(time.Millisecond), err)
+
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// delay returns the wait after the given failed attempt
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 {
+		d = min(d, p.MaxDelay)
+	}
+
+	spread := time.Duration(float64(d) * p.Jitter)
+	if spread <= 0 {
+		return d
+	}
+	return d - spread + rand.N(spread)
+}
+
+// isRetryable tells transient failures (5xx, 429, timeouts, refused or reset
+// connections, temporary DNS failures, Pub/Sub availability errors and SMTP
+// 4xx replies) from ones that will fail again
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code >= 400 && smtpErr.Code < 500
+	}
+
+	if isTransientNetError(err) {
+		return true
+	}
+
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded, codes.Internal:
+			return true
+		}
+	}
+
+	// A per-attempt timeout expired while the caller still has time
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// isTransientNetError reports whether a network error may go away on its
+// own. Every failed HTTP call is a net.Error (*url.Error), including bad
+// URLs and TLS certificate failures, so the type alone says nothing.
+func isTransientNetError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// httpError wraps err the way http.Client.Do reports a failed call
+func httpError(err error) error {
+	return fmt.Errorf("error calling webhook: %w", &url.Error{Op: "Post", URL: "https://example.com/hook", Err: err})
+}
+
+func dialError(errno syscall.Errno) error {
+	return &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: errno}}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"503", &StatusError{StatusCode: 503}, true},
+		{"429", &StatusError{StatusCode: 429}, true},
+		{"400", &StatusError{StatusCode: 400}, false},
+		{"redirect", &StatusError{StatusCode: 302}, false},
+		{"timeout", httpError(os.ErrDeadlineExceeded), true},
+		{"connection refused", httpError(dialError(syscall.ECONNREFUSED)), true},
+		{"connection reset", httpError(&net.OpError{Op: "read", Net: "tcp", Err: &os.SyscallError{Syscall: "read", Err: syscall.ECONNRESET}}), true},
+		{"temporary dns failure", httpError(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}}), true},
+		{"unknown host", httpError(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}}), false},
+		{"unsupported scheme", httpError(errors.New(`unsupported protocol scheme "ftp"`)), false},
+		{"untrusted certificate", httpError(x509.UnknownAuthorityError{}), false},
+		{"non-public address", httpError(&net.OpError{Op: "dial", Err: errors.New("webhook address 10.0.0.1 is not public")}), false},
+		{"network unreachable", httpError(dialError(syscall.ENETUNREACH)), false},
+		{"smtp 451", &textproto.Error{Code: 451, Msg: "try again later"}, true},
+		{"smtp 550", &textproto.Error{Code: 550, Msg: "no such user"}, false},
+		{"pubsub unavailable", status.Error(codes.Unavailable, "unavailable"), true},
+		{"pubsub invalid argument", status.Error(codes.InvalidArgument, "bad topic"), false},
+		{"attempt deadline", fmt.Errorf("publish: %w", context.DeadlineExceeded), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(context.Background(), tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRetryableOnceTheCallerGaveUp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if isRetryable(ctx, &StatusError{StatusCode: 503}) {
+		t.Error("retrying after the caller's context ended")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+)
+
+// minVolatilitySamples is the fewest observations a standard deviation is
+// computed from
+const minVolatilitySamples = 3
+
+// RuleResult is the outcome of evaluating a favorite. Value is what was
+// compared against Bound: the rate, the % change or the std dev. Upward
+// rules trigger when Value reaches Bound from below. Evaluated is false
+// when there was not enough history to measure Value.
+type RuleResult struct {
+	Evaluated bool
+	Triggered bool
+	Value     float64
+	Bound     float64
+	Upward    bool
+}
+
+// Rearms reports whether Value moved back past Bound by more than band
+// (relative), which re-enables a favorite that already notified
+func (r RuleResult) Rearms(band float64) bool {
+	if !r.Evaluated {
+		return false
+	}
+	margin := math.Abs(r.Bound) * band
+	if r.Upward {
+		return r.Value < r.Bound-margin
+	}
+	return r.Value > r.Bound+margin
+}
+
+// evaluateRule checks a favorite against the current rate and, for window
+// rules, the stored rate history
+func (s *Server) evaluateRule(ctx context.Context, fav domain.FavoriteConversion, rate float64) (RuleResult, error) {
+	switch fav.RuleType {
+	case domain.RulePctChange:
+		return s.evaluatePctChange(ctx, fav, rate)
+	case domain.RuleVolatility:
+		return s.evaluateVolatility(ctx, fav, rate)
+	default:
+		upward := fav.Direction != domain.DirectionBelow && fav.Direction != domain.DirectionCrossesDown
+		return RuleResult{
+			Evaluated: true,
+			Triggered: thresholdReached(fav, rate),
+			Value:     rate,
+			Bound:     fav.Threshold,
+			Upward:    upward,
+		}, nil
+	}
+}
+
+// thresholdReached reports whether rate satisfies the favorite's direction.
+// Crossing directions compare against the rate seen on the previous check
+// and never fire on the first one.
+func thresholdReached(fav domain.FavoriteConversion, rate float64) bool {
+	switch fav.Direction {
+	case domain.DirectionBelow:
+		return rate <= fav.Threshold
+	case domain.DirectionCrossesUp:
+		return fav.LastRate.Valid && fav.LastRate.Float64 < fav.Threshold && rate >= fav.Threshold
+	case domain.DirectionCrossesDown:
+		return fav.LastRate.Valid && fav.LastRate.Float64 > fav.Threshold && rate <= fav.Threshold
+	default:
+		return rate >= fav.Threshold
+	}
+}
+
+// evaluatePctChange compares rate with the oldest observation in the window.
+// above fires on rises of at least threshold %, below on falls.
+func (s *Server) evaluatePctChange(ctx context.Context, fav domain.FavoriteConversion, rate float64) (RuleResult, error) {
+	series, err := s.RateSeries(ctx, fav.CurrencyOrigin, fav.CurrencyDestination, time.Now().Add(-fav.Window))
+	if err != nil {
+		return RuleResult{}, err
+	}
+	if len(series) == 0 || series[0] == 0 {
+		return RuleResult{}, nil
+	}
+
+	change := (rate - series[0]) / series[0] * 100
+	result := RuleResult{Evaluated: true, Value: change, Bound: fav.Threshold, Upward: true}
+	if fav.Direction == domain.DirectionBelow {
+		result.Bound = -fav.Threshold
+		result.Upward = false
+		result.Triggered = change <= result.Bound
+	} else {
+		result.Triggered = change >= result.Bound
+	}
+	return result, nil
+}
+
+// evaluateVolatility computes the sample standard deviation of the rates in
+// the window. above fires when it exceeds threshold, below when it is under.
+func (s *Server) evaluateVolatility(ctx context.Context, fav domain.FavoriteConversion, rate float64) (RuleResult, error) {
+	series, err := s.RateSeries(ctx, fav.CurrencyOrigin, fav.CurrencyDestination, time.Now().Add(-fav.Window))
+	if err != nil {
+		return RuleResult{}, err
+	}
+	if n := len(series); n == 0 || series[n-1] != rate {
+		series = append(series, rate)
+	}
+	if len(series) < minVolatilitySamples {
+		return RuleResult{}, nil
+	}
+
+	stddev := sampleStdDev(series)
+	result := RuleResult{Evaluated: true, Value: stddev, Bound: fav.Threshold, Upward: fav.Direction != domain.DirectionBelow}
+	if result.Upward {
+		result.Triggered = stddev >= fav.Threshold
+	} else {
+		result.Triggered = stddev <= fav.Threshold
+	}
+	return result, nil
+}
+
+func sampleStdDev(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(squares / float64(len(values)-1))
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sharedconfig "github.com/joy-currency-conversion-GCP/shared/config"
+	"github.com/joy-currency-conversion-GCP/shared/dialect"
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+	"github.com/joy-currency-conversion-GCP/shared/favorites"
+	"github.com/joy-currency-conversion-GCP/shared/rates"
+	"github.com/joy-currency-conversion-GCP/shared/schema"
+	"github.com/joy-currency-conversion-GCP/worker/config"
+)
+
+// newHistoryServer returns a Server whose rate history holds the given EUR
+// to USD rates, each observed that long ago
+func newHistoryServer(t *testing.T, history map[time.Duration]float64) *Server {
+	t.Helper()
+	dbConfig := sharedconfig.DatabaseConfig{Driver: dialect.SQLite, Path: filepath.Join(t.TempDir(), "rules.db")}
+	db, err := dbConfig.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	if err := schema.Migrate(ctx, db, dialect.SQLite, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	d, _ := dialect.For(dialect.SQLite)
+	srv := NewServer(&config.Config{}, favorites.NewSQL(db, d), NewSQLOutbox(db, d), nil, nil, db, d)
+
+	for ago, usd := range history {
+		observedAt := time.Now().Add(-ago).UTC()
+		if usd == 0 {
+			// RecordRates skips non-positive rates, so store a broken one directly
+			insertZeroRate(t, db, observedAt)
+			continue
+		}
+		table := &rates.Table{Base: "EUR", Rates: map[string]float64{"USD": usd}, Source: "test", Timestamp: observedAt.Unix()}
+		if _, err := srv.RecordRates(ctx, table); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return srv
+}
+
+func insertZeroRate(t *testing.T, db *sql.DB, observedAt time.Time) {
+	t.Helper()
+	for quote, rate := range map[string]float64{"EUR": 1, "USD": 0} {
+		_, err := db.Exec(`INSERT INTO exchange_rates (base, quote, rate, provider, observed_at) VALUES ('EUR', ?, ?, 'test', ?)`,
+			quote, rate, observedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func windowFavorite(rule, direction string, threshold float64) domain.FavoriteConversion {
+	return domain.FavoriteConversion{
+		CurrencyOrigin:      "EUR",
+		CurrencyDestination: "USD",
+		RuleType:            rule,
+		Direction:           direction,
+		Threshold:           threshold,
+		Window:              time.Hour,
+	}
+}
+
+func TestEvaluateWindowRules(t *testing.T) {
+	tests := []struct {
+		name          string
+		history       map[time.Duration]float64
+		fav           domain.FavoriteConversion
+		rate          float64
+		wantEvaluated bool
+		wantTriggered bool
+		wantValue     float64
+	}{
+		{
+			name: "pct_change without history",
+			fav:  windowFavorite(domain.RulePctChange, domain.DirectionAbove, 5),
+			rate: 1.2,
+		},
+		{
+			name:    "pct_change ignores observations outside the window",
+			history: map[time.Duration]float64{2 * time.Hour: 1.0},
+			fav:     windowFavorite(domain.RulePctChange, domain.DirectionAbove, 5),
+			rate:    1.2,
+		},
+		{
+			name:    "pct_change with a zero base rate",
+			history: map[time.Duration]float64{30 * time.Minute: 0},
+			fav:     windowFavorite(domain.RulePctChange, domain.DirectionAbove, 5),
+			rate:    1.2,
+		},
+		{
+			name:          "pct_change rise past above",
+			history:       map[time.Duration]float64{50 * time.Minute: 1.0, 10 * time.Minute: 1.2},
+			fav:           windowFavorite(domain.RulePctChange, domain.DirectionAbove, 5),
+			rate:          1.06,
+			wantEvaluated: true, wantTriggered: true, wantValue: 6,
+		},
+		{
+			name:          "pct_change rise short of above",
+			history:       map[time.Duration]float64{30 * time.Minute: 1.0},
+			fav:           windowFavorite(domain.RulePctChange, domain.DirectionAbove, 5),
+			rate:          1.04,
+			wantEvaluated: true, wantValue: 4,
+		},
+		{
+			name:          "pct_change fall past below",
+			history:       map[time.Duration]float64{30 * time.Minute: 1.0},
+			fav:           windowFavorite(domain.RulePctChange, domain.DirectionBelow, 5),
+			rate:          0.94,
+			wantEvaluated: true, wantTriggered: true, wantValue: -6,
+		},
+		{
+			name:          "pct_change rise with below",
+			history:       map[time.Duration]float64{30 * time.Minute: 1.0},
+			fav:           windowFavorite(domain.RulePctChange, domain.DirectionBelow, 5),
+			rate:          1.06,
+			wantEvaluated: true, wantValue: 6,
+		},
+		{
+			name:    "volatility without a third sample",
+			history: map[time.Duration]float64{40 * time.Minute: 1.0, 20 * time.Minute: 1.1},
+			fav:     windowFavorite(domain.RuleVolatility, domain.DirectionAbove, 0.01),
+			rate:    1.1,
+		},
+		{
+			name:    "volatility with too few samples",
+			history: map[time.Duration]float64{30 * time.Minute: 1.0},
+			fav:     windowFavorite(domain.RuleVolatility, domain.DirectionAbove, 0.01),
+			rate:    1.1,
+		},
+		{
+			name:          "volatility past above",
+			history:       map[time.Duration]float64{40 * time.Minute: 1.0, 20 * time.Minute: 1.1},
+			fav:           windowFavorite(domain.RuleVolatility, domain.DirectionAbove, 0.05),
+			rate:          1.2,
+			wantEvaluated: true, wantTriggered: true, wantValue: 0.1,
+		},
+		{
+			name:          "volatility over below",
+			history:       map[time.Duration]float64{40 * time.Minute: 1.0, 20 * time.Minute: 1.1},
+			fav:           windowFavorite(domain.RuleVolatility, domain.DirectionBelow, 0.05),
+			rate:          1.2,
+			wantEvaluated: true, wantValue: 0.1,
+		},
+		{
+			name:          "flat rates under below",
+			history:       map[time.Duration]float64{45 * time.Minute: 1.1, 30 * time.Minute: 1.1, 15 * time.Minute: 1.1},
+			fav:           windowFavorite(domain.RuleVolatility, domain.DirectionBelow, 0.05),
+			rate:          1.1,
+			wantEvaluated: true, wantTriggered: true, wantValue: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newHistoryServer(t, tt.history)
+			result, err := srv.evaluateRule(context.Background(), tt.fav, tt.rate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Evaluated != tt.wantEvaluated || result.Triggered != tt.wantTriggered {
+				t.Fatalf("evaluated %v, triggered %v; want %v, %v", result.Evaluated, result.Triggered, tt.wantEvaluated, tt.wantTriggered)
+			}
+			if math.Abs(result.Value-tt.wantValue) > 1e-9 {
+				t.Errorf("value %v, want %v", result.Value, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestThresholdReached(t *testing.T) {
+	last := func(rate float64) sql.NullFloat64 { return sql.NullFloat64{Float64: rate, Valid: true} }
+
+	tests := []struct {
+		name      string
+		direction string
+		lastRate  sql.NullFloat64
+		rate      float64
+		want      bool
+	}{
+		{"above reached", domain.DirectionAbove, sql.NullFloat64{}, 1.1, true},
+		{"above not reached", domain.DirectionAbove, sql.NullFloat64{}, 1.09, false},
+		{"below reached", domain.DirectionBelow, sql.NullFloat64{}, 1.1, true},
+		{"below not reached", domain.DirectionBelow, sql.NullFloat64{}, 1.11, false},
+		{"crosses up", domain.DirectionCrossesUp, last(1.09), 1.11, true},
+		{"crosses up without a previous rate", domain.DirectionCrossesUp, sql.NullFloat64{}, 1.11, false},
+		{"stays above", domain.DirectionCrossesUp, last(1.12), 1.13, false},
+		{"crosses down", domain.DirectionCrossesDown, last(1.11), 1.09, true},
+		{"stays below", domain.DirectionCrossesDown, last(1.08), 1.07, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fav := domain.FavoriteConversion{Direction: tt.direction, Threshold: 1.1, LastRate: tt.lastRate}
+			if got := thresholdReached(fav, tt.rate); got != tt.want {
+				t.Errorf("thresholdReached = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRearms(t *testing.T) {
+	tests := []struct {
+		name   string
+		result RuleResult
+		want   bool
+	}{
+		{"not evaluated", RuleResult{Value: 0.5, Bound: 1.1, Upward: true}, false},
+		{"upward back past the band", RuleResult{Evaluated: true, Value: 1.08, Bound: 1.1, Upward: true}, true},
+		{"upward within the band", RuleResult{Evaluated: true, Value: 1.095, Bound: 1.1, Upward: true}, false},
+		{"upward still past the bound", RuleResult{Evaluated: true, Value: 1.2, Bound: 1.1, Upward: true}, false},
+		{"downward back past the band", RuleResult{Evaluated: true, Value: 1.02, Bound: 1.0}, true},
+		{"downward within the band", RuleResult{Evaluated: true, Value: 1.005, Bound: 1.0}, false},
+		{"negative bound back past the band", RuleResult{Evaluated: true, Value: -4, Bound: -5}, true},
+		{"negative bound within the band", RuleResult{Evaluated: true, Value: -4.97, Bound: -5}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.Rearms(0.01); got != tt.want {
+				t.Errorf("Rearms(0.01) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
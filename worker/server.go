@@ -0,0 +1,53 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/joy-currency-conversion-GCP/shared/dialect"
+	"github.com/joy-currency-conversion-GCP/shared/favorites"
+	"github.com/joy-currency-conversion-GCP/shared/rates"
+	"github.com/joy-currency-conversion-GCP/worker/config"
+)
+
+// Server is the worker with everything its handlers and commands depend on.
+// Each one has its own routes, so several can run side by side.
+type Server struct {
+	config    *config.Config
+	favorites favorites.Repository
+	outbox    OutboxStore
+	rates     rates.RateProvider
+	notifier  Notifier
+
+	// db holds the rate history. It may be nil, which leaves rates
+	// unrecorded and window rules unevaluated.
+	db      *sql.DB
+	dialect dialect.Dialect
+
+	mux *http.ServeMux
+}
+
+// NewServer builds a Server and registers its routes. The CLI commands need
+// neither provider nor notifier, so those may be nil there.
+func NewServer(cfg *config.Config, repo favorites.Repository, outbox OutboxStore, provider rates.RateProvider, notifier Notifier, db *sql.DB, d dialect.Dialect) *Server {
+	s := &Server{
+		config:    cfg,
+		favorites: repo,
+		outbox:    outbox,
+		rates:     provider,
+		notifier:  notifier,
+		db:        db,
+		dialect:   d,
+		mux:       http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/check-thresholds", s.checkThresholdsHandler)
+	s.mux.HandleFunc("/health", healthHandler)
+	s.mux.HandleFunc("/delete-all-favorites", s.deleteAllFavoritesHandler)
+	s.mux.HandleFunc("/dispatch-notifications", s.dispatchNotificationsHandler)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/dialect"
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+	"github.com/joy-currency-conversion-GCP/shared/favorites"
+	"github.com/joy-currency-conversion-GCP/shared/rates"
+	"github.com/joy-currency-conversion-GCP/worker/config"
+)
+
+// recordingNotifier keeps every notification it is asked to send
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []EmailNotification
+}
+
+func (n *recordingNotifier) SendNotification(ctx context.Context, notification EmailNotification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func (n *recordingNotifier) emails() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	emails := make([]string, 0, len(n.sent))
+	for _, notification := range n.sent {
+		emails = append(emails, notification.Email)
+	}
+	return emails
+}
+
+type testWorker struct {
+	*httptest.Server
+	favorites *favorites.MemoryRepository
+	notifier  *recordingNotifier
+}
+
+// newTestWorker runs a Server on in-memory favorites and outbox, without a
+// database, and a static provider quoting 1.1 US dollars per euro
+func newTestWorker(t *testing.T) *testWorker {
+	t.Helper()
+	cfg := &config.Config{
+		AlertCooldown:        time.Hour,
+		OutboxMaxAttempts:    3,
+		OutboxRetryBaseDelay: time.Second,
+		OutboxRetryMaxDelay:  time.Second,
+		OutboxLease:          time.Minute,
+	}
+	provider := rates.NewStaticProvider(&rates.Table{
+		Base:      "EUR",
+		Timestamp: time.Now().Unix(),
+		Rates:     map[string]float64{"EUR": 1, "USD": 1.1},
+		Source:    "static",
+	})
+
+	w := &testWorker{favorites: favorites.NewMemory(), notifier: &recordingNotifier{}}
+	w.Server = httptest.NewServer(NewServer(cfg, w.favorites, NewMemoryOutbox(w.favorites), provider, w.notifier, nil, dialect.Dialect{}))
+	t.Cleanup(w.Close)
+	return w
+}
+
+func (w *testWorker) watch(t *testing.T, email string, threshold float64) int64 {
+	t.Helper()
+	id, err := w.favorites.Create(context.Background(), &domain.FavoriteConversion{
+		Email:               email,
+		CurrencyOrigin:      "EUR",
+		CurrencyDestination: "USD",
+		Threshold:           threshold,
+		Direction:           domain.DirectionAbove,
+		RuleType:            domain.RuleThreshold,
+		Channel:             domain.ChannelEmail,
+	})
+	if err != nil {
+		t.Fatalf("create favorite: %v", err)
+	}
+	return id
+}
+
+func (w *testWorker) do(t *testing.T, method, path string) {
+	t.Helper()
+	req, err := http.NewRequest(method, w.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s %s: status %d", method, path, resp.StatusCode)
+	}
+}
+
+func TestWorkersNotifyOnlyTheirOwnFavorites(t *testing.T) {
+	a, b := newTestWorker(t), newTestWorker(t)
+	idA := a.watch(t, "ana@example.com", 1.0)
+	b.watch(t, "bea@example.com", 1.0)
+
+	a.do(t, http.MethodPost, "/check-thresholds")
+
+	if got := a.notifier.emails(); len(got) != 1 || got[0] != "ana@example.com" {
+		t.Errorf("a sent %v, want only ana@example.com", got)
+	}
+	if got := b.notifier.emails(); len(got) != 0 {
+		t.Errorf("b sent %v before being checked", got)
+	}
+
+	fav, err := a.favorites.Get(context.Background(), idA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fav.Armed || fav.LastNotifiedRate.Float64 != 1.1 {
+		t.Errorf("favorite kept armed %v, last notified rate %v after notifying", fav.Armed, fav.LastNotifiedRate)
+	}
+
+	// A disarmed favorite doesn't notify again
+	a.do(t, http.MethodPost, "/check-thresholds")
+	if got := a.notifier.emails(); len(got) != 1 {
+		t.Errorf("a sent %v after a second check, want one notification", got)
+	}
+
+	b.do(t, http.MethodPost, "/check-thresholds")
+	if got := b.notifier.emails(); len(got) != 1 || got[0] != "bea@example.com" {
+		t.Errorf("b sent %v, want only bea@example.com", got)
+	}
+}
+
+func TestWorkersDeleteOnlyTheirOwnFavorites(t *testing.T) {
+	a, b := newTestWorker(t), newTestWorker(t)
+	a.watch(t, "ana@example.com", 1.0)
+	b.watch(t, "bea@example.com", 1.0)
+
+	a.do(t, http.MethodDelete, "/delete-all-favorites")
+
+	ctx := context.Background()
+	if left, _ := a.favorites.ListAll(ctx); len(left) != 0 {
+		t.Errorf("a kept %d favorites", len(left))
+	}
+	if left, _ := b.favorites.ListAll(ctx); len(left) != 1 {
+		t.Errorf("b has %d favorites, want 1", len(left))
+	}
+}
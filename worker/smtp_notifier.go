@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"embed"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+	"github.com/joy-currency-conversion-GCP/worker/config"
+)
+
+//go:embed templates/alert_email.tmpl
+var emailTemplates embed.FS
+
+// SMTPNotifier sends alert emails straight from the worker, without the
+// Pub/Sub and Cloud Function hop
+type SMTPNotifier struct {
+	cfg     config.SMTPConfig
+	tmpl    *template.Template
+	timeout time.Duration
+}
+
+func NewSMTPNotifier(cfg config.SMTPConfig, timeout time.Duration) (*SMTPNotifier, error) {
+	var (
+		tmpl *template.Template
+		err  error
+	)
+	if cfg.TemplatePath != "" {
+		tmpl, err = template.ParseFiles(cfg.TemplatePath)
+	} else {
+		tmpl, err = template.ParseFS(emailTemplates, "templates/alert_email.tmpl")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing email template: %w", err)
+	}
+
+	for _, name := range []string{"subject", "body"} {
+		if tmpl.Lookup(name) == nil {
+			return nil, fmt.Errorf("email template must define %q", name)
+		}
+	}
+
+	return &SMTPNotifier{cfg: cfg, tmpl: tmpl, timeout: timeout}, nil
+}
+
+// alertMessage is the human-readable form of a notification shared by the
+// email template and the chat channels
+type alertMessage struct {
+	EmailNotification
+	Position string
+	Summary  string
+	Trend    string // e.g. "▲ +0.42%" against the previous check, empty if unknown
+}
+
+func newAlertMessage(n EmailNotification) alertMessage {
+	position := map[string]string{
+		domain.DirectionAbove:       "above",
+		domain.DirectionBelow:       "below",
+		domain.DirectionCrossesUp:   "above (it just crossed upwards)",
+		domain.DirectionCrossesDown: "below (it just crossed downwards)",
+	}[n.Direction]
+	if position == "" {
+		position = "above"
+	}
+
+	var summary string
+	switch n.Rule {
+	case domain.RulePctChange:
+		summary = fmt.Sprintf("The rate moved %+.2f%% within %s (your threshold: %v%%).", n.ObservedValue, n.Window, n.Threshold)
+	case domain.RuleVolatility:
+		summary = fmt.Sprintf("The %s standard deviation is %.4f, now %s your threshold.", n.Window, n.ObservedValue, position)
+	default:
+		summary = fmt.Sprintf("The current rate is now %s your configured threshold.", position)
+	}
+
+	return alertMessage{EmailNotification: n, Position: position, Summary: summary, Trend: trendIndicator(n.CurrentRate, n.PreviousRate)}
+}
+
+// trendIndicator shows where the rate moved since the previous check
+func trendIndicator(current, previous float64) string {
+	if previous == 0 {
+		return ""
+	}
+	change := (current - previous) / previous * 100
+	switch {
+	case change >= 0.005:
+		return fmt.Sprintf("▲ %+.2f%%", change)
+	case change <= -0.005:
+		return fmt.Sprintf("▼ %+.2f%%", change)
+	default:
+		return "▶ 0.00%"
+	}
+}
+
+func (n *SMTPNotifier) render(notification EmailNotification) (subject string, body []byte, err error) {
+	data := newAlertMessage(notification)
+
+	var buf bytes.Buffer
+	if err := n.tmpl.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return "", nil, fmt.Errorf("error rendering email subject: %w", err)
+	}
+	subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := n.tmpl.ExecuteTemplate(&buf, "body", data); err != nil {
+		return "", nil, fmt.Errorf("error rendering email body: %w", err)
+	}
+	return subject, buf.Bytes(), nil
+}
+
+func (n *SMTPNotifier) SendNotification(ctx context.Context, notification EmailNotification) error {
+	subject, body, err := n.render(notification)
+	if err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", notification.Email)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.Write(body)
+
+	client, err := n.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if n.cfg.Username != "" {
+		auth := smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("error authenticating to smtp server: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.cfg.From); err != nil {
+		return fmt.Errorf("error setting sender: %w", err)
+	}
+	if err := client.Rcpt(notification.Email); err != nil {
+		return fmt.Errorf("error setting recipient: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("error starting message: %w", err)
+	}
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return fmt.Errorf("error writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error sending message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// dial connects to the server and negotiates TLS as configured. The whole
+// conversation is bounded by the notifier timeout or ctx's deadline.
+func (n *SMTPNotifier) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port))
+	tlsConfig := &tls.Config{ServerName: n.cfg.Host}
+
+	deadline := time.Now().Add(n.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	dialer := &net.Dialer{Deadline: deadline}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if n.cfg.TLS == config.SMTPImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to smtp server: %w", err)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error starting smtp session: %w", err)
+	}
+
+	if n.cfg.TLS == config.SMTPStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("smtp server %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("error negotiating STARTTLS: %w", err)
+		}
+	}
+
+	return client, nil
+}
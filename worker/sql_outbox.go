@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/dialect"
+)
+
+// SQLOutbox keeps notifications in the notification_outbox table
+type SQLOutbox struct {
+	db      *sql.DB
+	dialect dialect.Dialect
+}
+
+func NewSQLOutbox(db *sql.DB, d dialect.Dialect) *SQLOutbox {
+	return &SQLOutbox{db: db, dialect: d}
+}
+
+func (o *SQLOutbox) Queue(ctx context.Context, rate float64, at time.Time, msg OutboxMessage) error {
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, o.dialect.Rebind(`
+		UPDATE favorite_conversions
+		SET armed = FALSE, last_notified_at = ?, last_notified_rate = ?
+		WHERE id = ?
+	`), at.UTC(), rate, msg.FavoriteID)
+	if err != nil {
+		return fmt.Errorf("error updating alert state: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, o.dialect.Rebind(`
+		INSERT INTO notification_outbox (favorite_id, payload, channel, target, status, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`), msg.FavoriteID, msg.Payload, msg.Channel, dialect.NullString(msg.Target), OutboxPending, at.UTC())
+	if err != nil {
+		return fmt.Errorf("error queueing notification: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing notification: %w", err)
+	}
+	return nil
+}
+
+// Claim marks the batch as sending in a short transaction; rows locked by a
+// concurrent claim are skipped
+func (o *SQLOutbox) Claim(ctx context.Context, now, leaseEnd time.Time, limit, maxAttempts int) ([]OutboxMessage, error) {
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, o.dialect.Rebind(`
+		UPDATE notification_outbox SET status = ?, last_error = ?
+		WHERE status = ? AND next_attempt_at <= ? AND attempts >= ?
+	`), OutboxFailed, "dispatcher lease expired on every attempt", OutboxSending, now.UTC(), maxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("error failing abandoned outbox rows: %w", err)
+	}
+	if abandoned, _ := res.RowsAffected(); abandoned > 0 {
+		log.Printf("❌ Giving up on %d notifications whose dispatcher never reported back after %d attempts", abandoned, maxAttempts)
+	}
+
+	rows, err := tx.QueryContext(ctx, o.dialect.Rebind(`
+		SELECT id, favorite_id, payload, channel, target, delivered_channels, attempts FROM notification_outbox
+		WHERE status IN (?, ?) AND next_attempt_at <= ?
+		ORDER BY id
+		LIMIT ?
+		`+o.dialect.SkipLocked+`
+	`), OutboxPending, OutboxSending, now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying outbox: %w", err)
+	}
+
+	var batch []OutboxMessage
+	for rows.Next() {
+		var msg OutboxMessage
+		var target, delivered sql.NullString
+		if err := rows.Scan(&msg.ID, &msg.FavoriteID, &msg.Payload, &msg.Channel, &target, &delivered, &msg.Attempts); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		msg.Target = target.String
+		if delivered.String != "" {
+			msg.Delivered = strings.Split(delivered.String, ",")
+		}
+		msg.Attempts++
+		batch = append(batch, msg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading outbox: %w", err)
+	}
+
+	for _, msg := range batch {
+		_, err := tx.ExecContext(ctx, o.dialect.Rebind(`
+			UPDATE notification_outbox SET status = ?, attempts = ?, next_attempt_at = ? WHERE id = ?
+		`), OutboxSending, msg.Attempts, leaseEnd.UTC(), msg.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error claiming outbox row %d: %w", msg.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing outbox claim: %w", err)
+	}
+	return batch, nil
+}
+
+// Record only updates the row while it is still sending under msg's claim.
+// Every claim counts an attempt, so the attempt number identifies the lease
+// (the lease deadline would too, but databases round stored timestamps).
+func (o *SQLOutbox) Record(ctx context.Context, msg OutboxMessage, result OutboxResult) error {
+	delivered := dialect.NullString(strings.Join(result.Delivered, ","))
+
+	var res sql.Result
+	var err error
+	switch result.Status {
+	case OutboxDelivered:
+		res, err = o.db.ExecContext(ctx, o.dialect.Rebind(`
+			UPDATE notification_outbox
+			SET status = ?, delivered_at = ?, last_error = NULL
+			WHERE id = ? AND status = ? AND attempts = ?
+		`), result.Status, result.DeliveredAt.UTC(), msg.ID, OutboxSending, msg.Attempts)
+	case OutboxFailed:
+		res, err = o.db.ExecContext(ctx, o.dialect.Rebind(`
+			UPDATE notification_outbox SET status = ?, last_error = ?, delivered_channels = ?
+			WHERE id = ? AND status = ? AND attempts = ?
+		`), result.Status, result.LastError, delivered, msg.ID, OutboxSending, msg.Attempts)
+	default:
+		res, err = o.db.ExecContext(ctx, o.dialect.Rebind(`
+			UPDATE notification_outbox SET status = ?, next_attempt_at = ?, last_error = ?, delivered_channels = ?
+			WHERE id = ? AND status = ? AND attempts = ?
+		`), result.Status, result.NextAttemptAt.UTC(), result.LastError, delivered, msg.ID, OutboxSending, msg.Attempts)
+	}
+	if err != nil {
+		return err
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting affected rows: %w", err)
+	}
+	if updated == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/joy-currency-conversion-GCP/shared/domain"
+	"github.com/joy-currency-conversion-GCP/shared/webhook"
+)
+
+// WebhookNotifier POSTs notifications as JSON to the favorite's target URL,
+// signed with the shared secret (see shared/webhook for verification). The
+// outbox id goes along as the delivery id, so receivers can spot retries.
+type WebhookNotifier struct {
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookNotifier builds a notifier that only connects to public
+// addresses and doesn't follow redirects, since anyone can create a favorite
+// and targets must not reach the worker's own network
+func NewWebhookNotifier(secret string, timeout time.Duration) *WebhookNotifier {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{Timeout: timeout, Control: dialPublicOnly}).DialContext
+
+	return &WebhookNotifier{
+		secret: []byte(secret),
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// dialPublicOnly refuses connections to non-public addresses. It runs on the
+// resolved address, so names pointing at internal hosts are caught too.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("webhook address %s: %w", address, err)
+	}
+	if !domain.IsPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("webhook address %s is not public", addrPort.Addr())
+	}
+	return nil
+}
+
+func (n *WebhookNotifier) SendNotification(ctx context.Context, notification EmailNotification) error {
+	if notification.Target == "" {
+		return fmt.Errorf("notification for %s has no webhook url", notification.Email)
+	}
+	if notification.DeliveryID == "" {
+		return fmt.Errorf("notification for %s has no delivery id", notification.Email)
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("error marshaling notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, notification.Target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	webhook.SignRequest(req, n.secret, notification.DeliveryID, body, time.Now())
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("error calling webhook: %w", &StatusError{StatusCode: resp.StatusCode})
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifierRefusesLocalAddresses(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	notifier := NewWebhookNotifier("secret", time.Second)
+	err := notifier.SendNotification(context.Background(), EmailNotification{
+		Email:      "ana@example.com",
+		Target:     srv.URL,
+		DeliveryID: "1",
+	})
+	if err == nil || !strings.Contains(err.Error(), "is not public") {
+		t.Fatalf("got %v, want the loopback address refused", err)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("the local server got %d requests", calls.Load())
+	}
+}
+
+func TestWebhookNotifierDoesNotFollowRedirects(t *testing.T) {
+	notifier := NewWebhookNotifier("secret", time.Second)
+	if err := notifier.client.CheckRedirect(nil, nil); err != http.ErrUseLastResponse {
+		t.Errorf("CheckRedirect = %v, want http.ErrUseLastResponse", err)
+	}
+}